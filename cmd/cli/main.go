@@ -1,76 +1,374 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/ghodss/yaml"
 	"github.com/gregjones/httpcache"
 	"github.com/gregjones/httpcache/diskcache"
+	"github.com/ischneider/go-wfs-client/export"
+	"github.com/ischneider/go-wfs-client/gen"
 	"github.com/ischneider/go-wfs-client/wfs"
+	"github.com/ischneider/go-wfs-client/wfstest"
 	flags "github.com/jessevdk/go-flags"
+	"github.com/peterh/liner"
 )
 
 var opts = &struct {
-	Encoding string `short:"e" long:"encoding" description:"specify the encoding" default:"application/json"`
-	Verbose  bool   `short:"v" long:"verbose" description:"be noisier"`
+	Encoding  []string `short:"e" long:"encoding" description:"media types to accept in order of preference, by default those declared by the operation"`
+	Verbose   bool     `short:"v" long:"verbose" description:"be noisier"`
+	HostLimit int      `long:"host-limit" description:"maximum number of concurrent requests per host" default:"4"`
+	NoPatch   bool     `long:"no-patch" description:"fail on specs referencing undefined components instead of patching them"`
+	Patches   []string `long:"components" description:"JSON file of components to patch specs with instead of the builtin WFS3 ones, may be repeated"`
+	Server    string   `long:"server" description:"server of the spec to use, by index from 0 or part of its description"`
+	ServerVar []string `long:"server-var" description:"name=value of a server URL variable, may be repeated"`
+	Spec      string   `long:"spec" description:"JSON or YAML spec file to use instead of requesting it, - for stdin; the service URL then defaults to the spec's server"`
 }{}
 
 func createClient() wfs.Client {
+	return newClient(os.Stderr)
+}
+
+// newClient creates the client configured by the global options, writing
+// warnings to w.
+func newClient(w io.Writer) wfs.Client {
 	cdir := os.Getenv("HTTP_CACHE_DIR")
 	if cdir == "" {
 		cdir = filepath.Join(os.TempDir(), "wfs-http-cache")
 	}
-	return wfs.NewClient(&http.Client{Transport: httpcache.NewTransport(diskcache.New(cdir))})
+	cl := wfs.NewClient(&http.Client{Transport: httpcache.NewTransport(diskcache.New(cdir))})
+	cl = cl.WithHostLimit(opts.HostLimit).WithLogger(log.New(w, "warning: ", 0))
+	vars := map[string]string{}
+	for _, v := range opts.ServerVar {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 {
+			fmt.Fprintln(w, "warning: skipping server variable without =", v)
+			continue
+		}
+		vars[parts[0]] = parts[1]
+	}
+	cl = cl.WithServer(opts.Server).WithServerVariables(vars)
+	if opts.NoPatch {
+		return cl.WithoutPatching()
+	}
+	var components [][]byte
+	for _, path := range opts.Patches {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(w, "warning: skipping components", err)
+			continue
+		}
+		components = append(components, b)
+	}
+	if len(components) > 0 {
+		cl = cl.WithFallbackComponents(components...)
+	}
+	return cl
 }
 
 func connect(svc string) (wfs.Service, error) {
-	cl := createClient()
-	fmt.Println("connecting to", svc)
+	return dial(createClient(), svc, os.Stderr)
+}
+
+// dial connects cl to svc as configured by the global options, writing
+// progress to w.
+func dial(cl wfs.Client, svc string, w io.Writer) (wfs.Service, error) {
+	if opts.Spec != "" {
+		return connectSpec(cl, svc, w)
+	}
+	// stdout may carry a feature stream
+	fmt.Fprintln(w, "connecting to", svc)
 	// @todo can we sniff out new/old style or make explicit via flag
-	return cl.Connect(svc, true)
+	s, err := cl.Connect(svc, true)
+	if err == nil && opts.Verbose {
+		fmt.Fprintln(w, "using spec", s.Info().SpecURL)
+	}
+	return s, err
+}
+
+// connectSpec connects using the spec file given by --spec, without
+// requesting it from the service.
+func connectSpec(cl wfs.Client, svc string, w io.Writer) (wfs.Service, error) {
+	var spec *wfs.Spec
+	var err error
+	if opts.Spec == "-" {
+		spec, err = cl.ReadSpec(os.Stdin)
+	} else {
+		spec, err = cl.LoadSpec(opts.Spec)
+	}
+	if err != nil {
+		return wfs.Service{}, err
+	}
+	s, err := cl.ConnectSpec(svc, true, spec)
+	if err == nil && opts.Verbose {
+		fmt.Fprintln(w, "using spec", s.Info().SpecURL, "for", s.Info().URL)
+	}
+	return s, err
+}
+
+// offline reports whether the service was given only by a spec file, in
+// which case nothing is requested from its servers unless asked for.
+func offline(svc string) bool {
+	return opts.Spec != "" && svc == ""
 }
 
 type Info struct {
-	Args struct {
+	Output string `long:"output" short:"o" description:"output format" choice:"text" choice:"json" choice:"yaml" choice:"markdown" default:"text"`
+	Args   struct {
 		Source string
 	} `positional-args:"y"`
 }
 
+// serviceReport is the output of info. Operations are sorted by path and
+// method so reports of different server versions can be diffed.
+type serviceReport struct {
+	URL         string             `json:"url"`
+	Description string             `json:"description,omitempty"`
+	PathStyle   string             `json:"pathStyle"`
+	SpecURL     string             `json:"specUrl"`
+	Conformance []string           `json:"conformance"`
+	Collections []collectionReport `json:"collections"`
+	Operations  []operationReport  `json:"operations"`
+	operations  []wfs.Operation
+}
+
+type collectionReport struct {
+	Name   string      `json:"name"`
+	Title  string      `json:"title,omitempty"`
+	Extent *wfs.Extent `json:"extent,omitempty"`
+}
+
+type operationReport struct {
+	ID          string            `json:"id"`
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	Tags        []string          `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	MediaTypes  []string          `json:"mediaTypes,omitempty"`
+	Body        []string          `json:"requestBody,omitempty"`
+	Parameters  []parameterReport `json:"parameters"`
+}
+
+type parameterReport struct {
+	Name        string        `json:"name"`
+	In          string        `json:"in"`
+	Type        string        `json:"type,omitempty"`
+	Required    bool          `json:"required"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+func isNotFound(err error) bool {
+	se, ok := err.(*wfs.StatusError)
+	return ok && se.StatusCode == http.StatusNotFound
+}
+
+// buildReport collects the service metadata. Services that do not serve a
+// conformance declaration or collections list are reported without them,
+// with a warning unless the resource is simply missing.
+func buildReport(ctx context.Context, svc wfs.Service, online bool) *serviceReport {
+	info := svc.Info()
+	r := &serviceReport{
+		URL:         info.URL,
+		Description: info.Description,
+		PathStyle:   info.PathStyle,
+		SpecURL:     info.SpecURL,
+		Conformance: []string{},
+		Collections: []collectionReport{},
+		Operations:  []operationReport{},
+	}
+	if online {
+		conf, err := svc.Conformance(ctx)
+		if err != nil && !isNotFound(err) {
+			fmt.Fprintln(os.Stderr, "warning: no conformance declaration:", err)
+		}
+		r.Conformance = append(r.Conformance, conf...)
+		sort.Strings(r.Conformance)
+		colls, err := svc.Collections(ctx)
+		if err != nil && !isNotFound(err) {
+			fmt.Fprintln(os.Stderr, "warning: no collections:", err)
+		}
+		for _, c := range colls {
+			extent := c.Extent
+			r.Collections = append(r.Collections, collectionReport{c.Name, c.Title, &extent})
+		}
+	}
+	r.operations = svc.Operations()
+	for _, op := range r.operations {
+		or := operationReport{
+			ID:          op.ID,
+			Method:      op.Method,
+			Path:        op.Path,
+			Tags:        op.Tags,
+			Description: op.Description,
+			Parameters:  []parameterReport{},
+		}
+		for _, t := range op.MediaTypes() {
+			or.MediaTypes = append(or.MediaTypes, t.Full)
+		}
+		if op.Body != nil {
+			or.Body = op.Body.ContentTypes
+		}
+		for _, p := range op.Params {
+			or.Parameters = append(or.Parameters, parameterReport{p.Name, p.In, p.Type, p.Required, p.Enum, p.Default, p.Description})
+		}
+		r.Operations = append(r.Operations, or)
+	}
+	return r
+}
+
 func (r *Info) Execute([]string) error {
 	svc, err := connect(r.Args.Source)
 	if err != nil {
 		return err
 	}
-	info := svc.Info()
+	report := buildReport(context.Background(), svc, !offline(r.Args.Source))
+	switch r.Output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "yaml":
+		b, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(b)
+		return err
+	case "markdown":
+		return report.writeMarkdown(os.Stdout)
+	}
 	fmt.Println("Service Info:")
-	fmt.Println("\tURL: ", info.URL)
-	fmt.Println("\tDescription: ", info.Description)
+	fmt.Println("\tURL: ", report.URL)
+	fmt.Println("\tDescription: ", report.Description)
+	fmt.Println("\tPath Style: ", report.PathStyle)
+	fmt.Println("\tSpec: ", report.SpecURL)
+	fmt.Println()
+	fmt.Println("Conformance:")
+	for _, c := range report.Conformance {
+		fmt.Println("\t", c)
+	}
+	fmt.Println()
+	fmt.Println("Collections:")
+	for _, c := range report.Collections {
+		fmt.Println("\t", c.Name, formatExtent(c.Extent))
+	}
 	fmt.Println()
-	ops := svc.Operations()
 	fmt.Println("Operations:")
-	for _, op := range ops {
-		fmt.Println("\tOperation: ", op.ID, "[", op.URL(), "]")
-		if opts.Verbose {
-			fmt.Println("\t", op.Description)
+	for _, op := range report.operations {
+		describeOperation(op, opts.Verbose)
+	}
+	return nil
+}
+
+func formatExtent(e *wfs.Extent) string {
+	if e == nil || e.BBox == (wfs.BBox{}) {
+		return ""
+	}
+	s := fmt.Sprintf("[%g, %g, %g, %g]", e.BBox[0], e.BBox[1], e.BBox[2], e.BBox[3])
+	if e.Crs != "" {
+		s += " " + e.Crs
+	}
+	return s
+}
+
+// writeMarkdown writes the report as a Markdown document.
+func (r *serviceReport) writeMarkdown(w io.Writer) error {
+	cell := func(v interface{}) string {
+		if v == nil {
+			return ""
 		}
-		fmt.Println("\tParameters:")
-		for _, p := range op.Params {
+		return strings.Replace(strings.Replace(fmt.Sprint(v), "|", "\\|", -1), "\n", " ", -1)
+	}
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "# %s\n\n", r.URL)
+	if r.Description != "" {
+		fmt.Fprintf(b, "%s\n\n", r.Description)
+	}
+	fmt.Fprintf(b, "Path style: %s\n\nSpec: %s\n\n## Conformance\n\n", r.PathStyle, r.SpecURL)
+	for _, c := range r.Conformance {
+		fmt.Fprintf(b, "- %s\n", c)
+	}
+	fmt.Fprintf(b, "\n## Collections\n\n| Name | Title | Extent |\n| --- | --- | --- |\n")
+	for _, c := range r.Collections {
+		fmt.Fprintf(b, "| %s | %s | %s |\n", cell(c.Name), cell(c.Title), formatExtent(c.Extent))
+	}
+	fmt.Fprintf(b, "\n## Operations\n")
+	for _, op := range r.Operations {
+		fmt.Fprintf(b, "\n### %s\n\n`%s %s`\n\n", op.ID, op.Method, op.Path)
+		if op.Description != "" {
+			fmt.Fprintf(b, "%s\n\n", op.Description)
+		}
+		if len(op.MediaTypes) > 0 {
+			fmt.Fprintf(b, "Media types: %s\n\n", strings.Join(op.MediaTypes, ", "))
+		}
+		if len(op.Body) > 0 {
+			fmt.Fprintf(b, "Request body: %s\n\n", strings.Join(op.Body, ", "))
+		}
+		if len(op.Parameters) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "| Name | In | Type | Required | Enum | Default | Description |\n| --- | --- | --- | --- | --- | --- | --- |\n")
+		for _, p := range op.Parameters {
+			var enum []string
+			for _, e := range p.Enum {
+				enum = append(enum, cell(e))
+			}
 			required := ""
 			if p.Required {
-				required = "*Required*"
-			}
-			fmt.Print("\t\t", p.Name, "["+p.Type+"]", required)
-			if opts.Verbose {
-				fmt.Print(p.Description)
+				required = "yes"
 			}
-			fmt.Println()
-			fmt.Println()
+			fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s | %s |\n", cell(p.Name), p.In, p.Type, required, strings.Join(enum, ", "), cell(p.Default), cell(p.Description))
 		}
 	}
-	return nil
+	_, err := b.WriteTo(w)
+	return err
+}
+
+func describeOperation(op wfs.Operation, verbose bool) {
+	fmt.Println("\tOperation: ", op.ID, "[", op.Method, op.URL(), "]")
+	var types []string
+	for _, t := range op.MediaTypes() {
+		types = append(types, t.Full)
+	}
+	fmt.Println("\tMedia Types: ", strings.Join(types, ", "))
+	if op.Body != nil {
+		required := ""
+		if op.Body.Required {
+			required = " *Required*"
+		}
+		fmt.Println("\tRequest Body: ", strings.Join(op.Body.ContentTypes, ", ")+required)
+	}
+	if verbose {
+		fmt.Println("\t", op.Description)
+	}
+	fmt.Println("\tParameters:")
+	for _, p := range op.Params {
+		required := ""
+		if p.Required {
+			required = "*Required*"
+		}
+		fmt.Print("\t\t", p.Name, "["+p.Type+"]", required)
+		if verbose {
+			fmt.Print(p.Description)
+		}
+		fmt.Println()
+		fmt.Println()
+	}
 }
 
 type Collections struct {
@@ -88,22 +386,539 @@ func (r Collections) Execute([]string) error {
 	if err != nil {
 		return err
 	}
-	return op.SimpleCall().Accept(opts.Encoding).ExecuteWriter(os.Stdout)
+	return op.SimpleCall().Accept(opts.Encoding...).ExecuteWriter(os.Stdout)
 }
 
-type Operation struct {
+// seqOptions select streaming one feature per line across all pages instead
+// of writing a single response.
+type seqOptions struct {
+	NDJSON bool `long:"ndjson" description:"stream the features of all pages as newline-delimited GeoJSON"`
+	RS     bool `long:"rs" description:"stream as a GeoJSON text sequence (RFC 8142), prefixing features with a record separator"`
+}
+
+func (s seqOptions) stream() bool {
+	return s.NDJSON || s.RS
+}
+
+func (s seqOptions) writer() *export.GeoJSONSeq {
+	return export.NewGeoJSONSeq(os.Stdout, s.RS)
+}
+
+type Items struct {
+	seqOptions
+	Parallel     int    `long:"parallel" description:"number of pages to fetch concurrently when streaming, if the server supports offsets" default:"1"`
+	Limit        int    `long:"limit" description:"maximum number of features to return"`
+	BBox         string `long:"bbox" description:"bounding box as lx,ly,ux,uy (longitude or easting first)"`
+	BBoxCrs      string `long:"bbox-crs" description:"CRS of the bounding box, e.g. EPSG:3857"`
+	Crs          string `long:"crs" description:"CRS of the returned geometries, e.g. EPSG:4326"`
+	ToCrs        string `long:"to-crs" description:"reproject the returned geometries locally, e.g. EPSG:3857"`
+	Properties   string `long:"properties" description:"comma separated properties to return"`
+	SortBy       string `long:"sortby" description:"comma separated properties to sort by, prefix with - to sort descending, as in --sortby=-pop"`
+	SkipGeometry bool   `long:"skip-geometry" description:"omit feature geometries"`
+	Format       string `long:"format" description:"media type to request, e.g. gml for XML-only services"`
+	Args         struct {
+		Source     string
+		Collection string
+	} `positional-args:"y"`
+}
+
+func (r Items) Execute([]string) error {
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	q := wfs.ItemsQuery{
+		Limit:        r.Limit,
+		BBoxCrs:      r.BBoxCrs,
+		Crs:          r.Crs,
+		SortBy:       wfs.ParseSortBy(r.SortBy),
+		SkipGeometry: r.SkipGeometry,
+		Format:       r.Format,
+	}
+	if r.BBox != "" {
+		b, err := wfs.ParseBBox(r.BBox)
+		if err != nil {
+			return err
+		}
+		q.BBox = &b
+	}
+	if r.Properties != "" {
+		q.Properties = strings.Split(r.Properties, ",")
+	}
+	ctx := context.Background()
+	if r.stream() {
+		w := r.writer()
+		pages := svc.Collection(r.Args.Collection).Pages(ctx, q).Parallel(r.Parallel).Reproject(r.ToCrs)
+		if _, err := export.WritePages(w, pages); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+	fc, err := svc.Collection(r.Args.Collection).Items(ctx, q)
+	if err != nil {
+		return err
+	}
+	if r.ToCrs != "" {
+		if err := fc.Reproject(r.ToCrs); err != nil {
+			return err
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+type Item struct {
+	Workers int `long:"workers" description:"number of concurrent requests" default:"8"`
+	Args    struct {
+		Source     string
+		Collection string
+		IDs        []string `required:"1"`
+	} `positional-args:"y"`
+}
+
+func (r Item) Execute([]string) error {
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	results := svc.Collection(r.Args.Collection).ItemsByID(context.Background(), r.Workers, r.Args.IDs...)
+	failed := 0
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Fprintf(os.Stderr, "error fetching %s : %s\n", res.ID, res.Err)
+			failed++
+			continue
+		}
+		if opts.Verbose && res.ETag != "" {
+			fmt.Fprintf(os.Stderr, "%s etag %s\n", res.ID, res.ETag)
+		}
+		if err := enc.Encode(res.Feature); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d features could not be fetched", failed, len(results))
+	}
+	return nil
+}
+
+type Gen struct {
+	Package string `long:"package" short:"p" description:"name of the generated package" default:"client"`
+	Output  string `long:"output" short:"o" description:"file to write the package to, stdout if omitted"`
+	Args    struct {
+		Source string
+	} `positional-args:"y"`
+}
+
+func (g Gen) Execute([]string) error {
+	svc, err := connect(g.Args.Source)
+	if err != nil {
+		return err
+	}
+	src, err := gen.Generate(context.Background(), svc, gen.Options{Package: g.Package, Offline: offline(g.Args.Source)})
+	if err != nil {
+		return err
+	}
+	if g.Output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(g.Output, src, 0644)
+}
+
+type Serve struct {
+	Addr        string        `long:"addr" description:"address to listen on" default:"localhost:8080"`
+	OldStyle    bool          `long:"old-style" description:"serve the old path layout, collections at /<collection>"`
+	Latency     time.Duration `long:"latency" description:"delay of every response, such as 200ms"`
+	ErrorRate   float64       `long:"error-rate" description:"fraction of requests, from 0 to 1, failing with --error-status"`
+	ErrorStatus int           `long:"error-status" description:"status of injected errors" default:"503"`
+	Args        struct {
+		Files []string `required:"1"`
+	} `positional-args:"y"`
+}
+
+func (s Serve) Execute([]string) error {
+	cfg := wfstest.Config{
+		OldStyle:    s.OldStyle,
+		Latency:     s.Latency,
+		ErrorRate:   s.ErrorRate,
+		ErrorStatus: s.ErrorStatus,
+	}
+	for _, path := range s.Args.Files {
+		c, err := wfstest.LoadCollection(path)
+		if err != nil {
+			return err
+		}
+		cfg.Collections = append(cfg.Collections, c)
+	}
+	fmt.Fprintf(os.Stderr, "serving %d collections at http://%s/\n", len(cfg.Collections), s.Addr)
+	return http.ListenAndServe(s.Addr, wfstest.Handler(cfg))
+}
+
+type Export struct {
+	Limit int `long:"limit" description:"number of features to request per page"`
+	Args  struct {
+		Source      string
+		File        string
+		Collections []string
+	} `positional-args:"y"`
+}
+
+func (e Export) Execute([]string) error {
+	svc, err := connect(e.Args.Source)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	names := e.Args.Collections
+	if len(names) == 0 {
+		infos, err := svc.Collections(ctx)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(e.Args.File))
+	if ext == ".gpkg" {
+		return e.geoPackage(ctx, svc, names)
+	}
+	for _, name := range names {
+		path := e.Args.File
+		if len(names) > 1 {
+			path = strings.TrimSuffix(path, filepath.Ext(path)) + "_" + name + ext
+		}
+		var w export.FeatureWriter
+		n, err := export.WriteCollection(ctx, svc.Collection(name), e.query(), func(l export.Layer) (export.FeatureWriter, error) {
+			var err error
+			w, err = export.Create(path, l)
+			return w, err
+		})
+		printWarnings(name, w)
+		if err != nil {
+			return fmt.Errorf("error exporting %s : %s", name, err)
+		}
+		fmt.Println("exported", n, "features from", name, "to", path)
+	}
+	return nil
+}
+
+func (e Export) query() wfs.ItemsQuery {
+	return wfs.ItemsQuery{Limit: e.Limit}
+}
+
+func (e Export) geoPackage(ctx context.Context, svc wfs.Service, names []string) error {
+	gp, err := export.CreateGeoPackage(e.Args.File)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var w export.FeatureWriter
+		n, err := export.WriteCollection(ctx, svc.Collection(name), e.query(), func(l export.Layer) (export.FeatureWriter, error) {
+			layer, err := gp.CreateLayer(l)
+			if err != nil {
+				return nil, err
+			}
+			w = layer
+			return w, nil
+		})
+		printWarnings(name, w)
+		if err != nil {
+			gp.Discard()
+			return fmt.Errorf("error exporting %s : %s", name, err)
+		}
+		fmt.Println("exported", n, "features from", name)
+	}
+	return gp.Close()
+}
+
+// printWarnings reports lossy conversions of writers implementing
+// export.Warner.
+func printWarnings(name string, w export.FeatureWriter) {
+	if wr, ok := w.(export.Warner); ok {
+		for _, msg := range wr.Warnings() {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", name, msg)
+		}
+	}
+}
+
+type Harvest struct {
+	Limit int    `long:"limit" description:"number of features to request per page"`
+	State string `long:"state" description:"state file recording the progress, by default harvest-state.json in the output directory"`
+	Args  struct {
+		Source      string
+		Dir         string
+		Collections []string
+	} `positional-args:"y"`
+}
+
+func (h Harvest) Execute([]string) error {
+	svc, err := connect(h.Args.Source)
+	if err != nil {
+		return err
+	}
+	// stop between pages on interrupt, the state file allows resuming
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		fmt.Fprintln(os.Stderr, "interrupted, saving state")
+		cancel()
+	}()
+	if err := os.MkdirAll(h.Args.Dir, 0755); err != nil {
+		return err
+	}
+	statePath := h.State
+	if statePath == "" {
+		statePath = filepath.Join(h.Args.Dir, "harvest-state.json")
+	}
+	state, err := export.LoadHarvestState(statePath)
+	if err != nil {
+		return err
+	}
+	names := h.Args.Collections
+	if len(names) == 0 {
+		infos, err := svc.Collections(ctx)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+	}
+	for _, name := range names {
+		st := state.Collection(name)
+		if st.Done {
+			fmt.Println("skipping", name, "harvested", st.Features, "features")
+			continue
+		}
+		path := filepath.Join(h.Args.Dir, name+".ndjson")
+		err := export.HarvestCollection(ctx, svc.Collection(name), wfs.ItemsQuery{Limit: h.Limit}, path, st, func() error {
+			fmt.Fprintf(os.Stderr, "\r%s: %d features", name, st.Features)
+			return state.Save(statePath)
+		})
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("error harvesting %s : %s", name, err)
+		}
+	}
+	return nil
+}
+
+type Sync struct {
+	Snapshot string `long:"snapshot" description:"snapshot file of the previous sync, by default <COLLECTION>.snapshot.json"`
+	Baseline string `long:"baseline" description:"newline-delimited GeoJSON of a previous harvest to compare against when there is no snapshot yet"`
+	Since    string `long:"since" description:"datetime filter narrowing the scan, e.g. 2018-02-12T00:00:00Z/.. (removals are not detected)"`
+	Format   string `long:"format" description:"output format" choice:"summary" choice:"geojson" default:"summary"`
+	DryRun   bool   `long:"dry-run" description:"report changes without updating the snapshot"`
+	Args     struct {
+		Source     string
+		Collection string
+	} `positional-args:"y"`
+}
+
+func (r Sync) Execute([]string) error {
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	path := r.Snapshot
+	if path == "" {
+		path = r.Args.Collection + ".snapshot.json"
+	}
+	prev, err := export.LoadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	if len(prev.Features) == 0 && r.Baseline != "" {
+		f, err := os.Open(r.Baseline)
+		if err != nil {
+			return err
+		}
+		prev, err = export.ReadSnapshot(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("error reading %s : %s", r.Baseline, err)
+		}
+	}
+	q := wfs.ItemsQuery{Datetime: r.Since}
+	diff, next, err := export.Sync(context.Background(), svc.Collection(r.Args.Collection), q, prev)
+	if err != nil {
+		return err
+	}
+	if r.Format == "geojson" {
+		err = diff.WriteGeoJSON(os.Stdout)
+	} else {
+		err = diff.WriteSummary(os.Stdout)
+	}
+	if err != nil || r.DryRun {
+		return err
+	}
+	return next.Save(path)
+}
+
+// readInput reads the file at path, or stdin if path is empty or "-".
+func readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+type Create struct {
 	Args struct {
+		Source     string
+		Collection string
+		File       string
+	} `positional-args:"y"`
+}
+
+func (r Create) Execute([]string) error {
+	b, err := readInput(r.Args.File)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Type     string        `json:"type"`
+		Features []wfs.Feature `json:"features"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("error reading GeoJSON : %s", err)
+	}
+	features := doc.Features
+	switch doc.Type {
+	case "Feature":
+		var f wfs.Feature
+		if err := json.Unmarshal(b, &f); err != nil {
+			return fmt.Errorf("error reading GeoJSON : %s", err)
+		}
+		features = []wfs.Feature{f}
+	case "FeatureCollection":
+	default:
+		return fmt.Errorf("expected a GeoJSON Feature or FeatureCollection, got %q", doc.Type)
+	}
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	coll := svc.Collection(r.Args.Collection)
+	for i, f := range features {
+		ref, err := coll.Create(context.Background(), f)
+		if err != nil {
+			return fmt.Errorf("error creating feature %d of %d : %s", i+1, len(features), err)
+		}
+		fmt.Printf("%s\t%s\n", ref.ID, ref.Location)
+	}
+	return nil
+}
+
+type Update struct {
+	Replace bool   `long:"replace" description:"replace the feature with the given one instead of applying a JSON merge patch"`
+	IfMatch string `long:"if-match" description:"ETag the feature must still have for the update to apply"`
+	Args    struct {
+		Source     string
+		Collection string
+		ID         string
+		File       string
+	} `positional-args:"y"`
+}
+
+func (r Update) Execute([]string) error {
+	b, err := readInput(r.Args.File)
+	if err != nil {
+		return err
+	}
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	coll := svc.Collection(r.Args.Collection)
+	var ref wfs.ItemRef
+	if r.Replace {
+		var f wfs.Feature
+		if err := json.Unmarshal(b, &f); err != nil {
+			return fmt.Errorf("error reading GeoJSON : %s", err)
+		}
+		ref, err = coll.Replace(context.Background(), r.Args.ID, f, r.IfMatch)
+	} else {
+		if !json.Valid(b) {
+			return fmt.Errorf("merge patch is not valid JSON")
+		}
+		ref, err = coll.Update(context.Background(), r.Args.ID, json.RawMessage(b), r.IfMatch)
+	}
+	if wfs.IsPreconditionFailed(err) {
+		return fmt.Errorf("feature %s has changed since %s", r.Args.ID, r.IfMatch)
+	}
+	if err != nil {
+		return err
+	}
+	if ref.ETag != "" {
+		fmt.Fprintln(os.Stderr, "etag", ref.ETag)
+	}
+	return nil
+}
+
+type Delete struct {
+	IfMatch string `long:"if-match" description:"ETag the feature must still have to be deleted"`
+	Args    struct {
+		Source     string
+		Collection string
+		IDs        []string `required:"1"`
+	} `positional-args:"y"`
+}
+
+func (r Delete) Execute([]string) error {
+	if r.IfMatch != "" && len(r.Args.IDs) > 1 {
+		return fmt.Errorf("--if-match applies to a single feature, got %d IDs", len(r.Args.IDs))
+	}
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	coll := svc.Collection(r.Args.Collection)
+	for _, id := range r.Args.IDs {
+		err := coll.Delete(context.Background(), id, r.IfMatch)
+		if wfs.IsPreconditionFailed(err) {
+			return fmt.Errorf("feature %s has changed since %s", id, r.IfMatch)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Operation struct {
+	seqOptions
+	Body        string `long:"body" description:"file holding the request body, - for stdin"`
+	ContentType string `long:"content-type" description:"media type of the request body, required if the operation accepts several"`
+	Args        struct {
 		Source    string
 		Operation string
 	} `positional-args:"y"`
 }
 
+// findOperation looks up an operation by ID or, given as "METHOD /path",
+// by method and path.
+func findOperation(svc wfs.Service, id string) (wfs.Operation, error) {
+	if parts := strings.Fields(id); len(parts) == 2 && strings.HasPrefix(parts[1], "/") {
+		return svc.OperationByPath(parts[0], parts[1])
+	}
+	return svc.GetOperation(id)
+}
+
 func (o Operation) Execute(args []string) error {
 	svc, err := connect(o.Args.Source)
 	if err != nil {
 		return err
 	}
-	op, err := svc.GetOperation(o.Args.Operation)
+	op, err := findOperation(svc, o.Args.Operation)
 	if err != nil {
 		return err
 	}
@@ -119,7 +934,469 @@ func (o Operation) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
-	return call.Accept(opts.Encoding).ExecuteWriter(os.Stdout)
+	if o.Body != "" {
+		b, err := readInput(o.Body)
+		if err != nil {
+			return err
+		}
+		if call, err = call.WithBody(o.ContentType, b); err != nil {
+			return err
+		}
+	}
+	if o.stream() {
+		w := o.writer()
+		if _, err := export.WritePages(w, call.Pages(context.Background())); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+	return call.Accept(opts.Encoding...).ExecuteWriter(os.Stdout)
+}
+
+// Shell is an interactive session on a single service, so the spec is only
+// loaded once.
+type Shell struct {
+	History string `long:"history" description:"history file, by default ~/.wfs_history"`
+	Args    struct {
+		Source string
+	} `positional-args:"y"`
+}
+
+const shellHelp = `Commands:
+	ops               list operations
+	describe [OP]     describe an operation, by default the current one
+	use OP            select the operation to run, clearing parameters
+	set NAME=VALUE    set a parameter of the current operation
+	unset NAME        remove a parameter
+	params            show the parameters set
+	body [FILE]       send FILE as request body, or none if omitted
+	run [OP]          run the current operation, or select OP and run it
+	help              show this help
+	quit              leave the shell`
+
+var shellCommands = []string{"ops", "describe", "use", "set", "unset", "params", "body", "run", "help", "quit"}
+
+// shellState is the operation being prepared in a Shell.
+type shellState struct {
+	svc    wfs.Service
+	ops    map[string]wfs.Operation
+	ids    []string
+	op     *wfs.Operation
+	params map[string]string
+	body   string
+}
+
+func (r Shell) Execute([]string) error {
+	svc, err := connect(r.Args.Source)
+	if err != nil {
+		return err
+	}
+	st := &shellState{svc: svc, ops: map[string]wfs.Operation{}, params: map[string]string{}}
+	for _, op := range svc.Operations() {
+		st.ops[op.ID] = op
+		st.ids = append(st.ids, op.ID)
+	}
+	sort.Strings(st.ids)
+
+	history := r.History
+	if history == "" {
+		history = filepath.Join(os.Getenv("HOME"), ".wfs_history")
+	}
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetCompleter(st.complete)
+	if f, err := os.Open(history); err == nil {
+		line.ReadHistory(f)
+		f.Close()
+	}
+	defer func() {
+		if f, err := os.Create(history); err == nil {
+			line.WriteHistory(f)
+			f.Close()
+		}
+	}()
+
+	fmt.Println(len(st.ids), "operations, type help for commands")
+	for {
+		input, err := line.Prompt(st.prompt())
+		if err == liner.ErrPromptAborted || err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		line.AppendHistory(input)
+		fields := strings.Fields(input)
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+		if err := st.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+	}
+}
+
+func (st *shellState) prompt() string {
+	if st.op == nil {
+		return "wfs> "
+	}
+	return st.op.ID + "> "
+}
+
+// complete completes command names, operation IDs and the parameter names
+// of the current operation.
+func (st *shellState) complete(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || (len(fields) == 1 && !strings.HasSuffix(line, " ")) {
+		return prefixed("", shellCommands, line)
+	}
+	cmd, prefix := fields[0], ""
+	if !strings.HasSuffix(line, " ") {
+		prefix = fields[len(fields)-1]
+	}
+	head := line[:len(line)-len(prefix)]
+	switch cmd {
+	case "describe", "use", "run":
+		return prefixed(head, st.ids, prefix)
+	case "set", "unset":
+		if st.op == nil {
+			return nil
+		}
+		var names []string
+		for _, p := range st.op.Params {
+			if cmd == "set" {
+				names = append(names, p.Name+"=")
+			} else if _, ok := st.params[p.Name]; ok {
+				names = append(names, p.Name)
+			}
+		}
+		return prefixed(head, names, prefix)
+	}
+	return nil
+}
+
+func prefixed(head string, candidates []string, prefix string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, head+c)
+		}
+	}
+	return out
+}
+
+func (st *shellState) operation(id string) (wfs.Operation, error) {
+	if id == "" {
+		if st.op == nil {
+			return wfs.Operation{}, fmt.Errorf("no operation selected, see use")
+		}
+		return *st.op, nil
+	}
+	op, ok := st.ops[id]
+	if !ok {
+		return wfs.Operation{}, fmt.Errorf("no operation %q", id)
+	}
+	return op, nil
+}
+
+func (st *shellState) exec(cmd string, args []string) error {
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	switch cmd {
+	case "help":
+		fmt.Println(shellHelp)
+	case "ops":
+		for _, id := range st.ids {
+			op := st.ops[id]
+			fmt.Printf("%-30s %-6s %s\n", id, op.Method, op.Path)
+		}
+	case "describe":
+		op, err := st.operation(arg)
+		if err != nil {
+			return err
+		}
+		describeOperation(op, true)
+	case "use":
+		op, err := st.operation(arg)
+		if err != nil {
+			return err
+		}
+		st.op, st.params, st.body = &op, map[string]string{}, ""
+	case "set":
+		if st.op == nil {
+			return fmt.Errorf("no operation selected, see use")
+		}
+		for _, a := range args {
+			parts := strings.SplitN(a, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("set requires name=value, got %q", a)
+			}
+			st.params[parts[0]] = parts[1]
+		}
+	case "unset":
+		for _, a := range args {
+			delete(st.params, a)
+		}
+	case "params":
+		names := make([]string, 0, len(st.params))
+		for k := range st.params {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Printf("%s=%s\n", k, st.params[k])
+		}
+	case "body":
+		st.body = arg
+	case "run":
+		if arg != "" {
+			if err := st.exec("use", args); err != nil {
+				return err
+			}
+		}
+		return st.run()
+	default:
+		return fmt.Errorf("unknown command %q, type help for commands", cmd)
+	}
+	return nil
+}
+
+// run executes the current operation, indenting JSON responses.
+func (st *shellState) run() error {
+	op, err := st.operation("")
+	if err != nil {
+		return err
+	}
+	params := map[string]interface{}{}
+	for k, v := range st.params {
+		params[k] = v
+	}
+	call, err := op.Call(params)
+	if err != nil {
+		return err
+	}
+	if st.body != "" {
+		b, err := ioutil.ReadFile(st.body)
+		if err != nil {
+			return err
+		}
+		if call, err = call.WithBody("", b); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	if err := call.Accept(opts.Encoding...).ExecuteWriter(&buf); err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if json.Indent(&out, buf.Bytes(), "", "  ") != nil {
+		out = buf
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
+
+// Completion writes a completion script for a shell. The scripts call the
+// hidden __complete command with the words typed so far.
+type Completion struct {
+	Name string `long:"name" description:"program name to complete, by default the name of this program"`
+	Args struct {
+		Shell string `description:"bash, zsh or fish"`
+	} `positional-args:"y" required:"yes"`
+}
+
+const bashCompletion = `_{{fn}}() {
+	local line="${COMP_LINE:0:$COMP_POINT}" words
+	read -ra words <<< "$line"
+	[[ "$line" == *" " ]] && words+=("")
+	local cur="${words[${#words[@]}-1]}" IFS=$'\n'
+	COMPREPLY=($("${words[0]}" __complete -- "${words[@]:1}" 2>/dev/null))
+	# bash splits URLs and name=value at : and =, only replace the last part
+	local prefix="${cur%"${COMP_WORDS[COMP_CWORD]}"}"
+	COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+	[[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == *= ]] && compopt -o nospace
+	return 0
+}
+complete -o default -F _{{fn}} {{name}}
+`
+
+const zshCompletion = `#compdef {{name}}
+_{{fn}}() {
+	local -a items
+	items=("${(@f)$(${words[1]} __complete -- "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+	local item
+	for item in $items; do
+		[[ -z "$item" ]] && continue
+		if [[ "$item" == *= ]]; then
+			compadd -Q -S '' -- "$item"
+		else
+			compadd -Q -- "$item"
+		fi
+	done
+	[[ ${#items} -eq 0 || -z "$items" ]] && _files
+}
+compdef _{{fn}} {{name}}
+`
+
+const fishCompletion = `function __{{fn}}_complete
+	set -l words (commandline -opc) (commandline -ct)
+	$words[1] __complete -- $words[2..-1] 2>/dev/null
+end
+complete -c {{name}} -a '(__{{fn}}_complete)'
+`
+
+func (c Completion) Execute([]string) error {
+	name := c.Name
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+	var script string
+	switch c.Args.Shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", c.Args.Shell)
+	}
+	fn := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+	script = strings.Replace(script, "{{fn}}", fn, -1)
+	_, err := fmt.Print(strings.Replace(script, "{{name}}", name, -1))
+	return err
+}
+
+// completeHook is the hidden command called by the completion scripts with
+// the words of the command line up to the one being completed.
+type completeHook struct{}
+
+func (completeHook) Execute(args []string) error {
+	for _, c := range completeWords(args) {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+// completeWords completes the last of words. Commands, options and their
+// choices are completed by go-flags; for op, the operation IDs and parameter
+// names are read from the spec of the service given on the command line.
+func completeWords(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	var out []string
+	parser := buildParser()
+	parser.CompletionHandler = func(items []flags.Completion) {
+		for _, it := range items {
+			out = append(out, it.Item)
+		}
+	}
+	os.Setenv("GO_FLAGS_COMPLETION", "1")
+	parser.ParseArgs(words)
+	os.Unsetenv("GO_FLAGS_COMPLETION")
+	return append(out, completeOperation(parser, words)...)
+}
+
+func completeOperation(parser *flags.Parser, words []string) []string {
+	cmd := parser.Find("op")
+	last := words[len(words)-1]
+	if strings.HasPrefix(last, "-") {
+		return nil
+	}
+	inOp := false
+	var positional []string
+	for i := 0; i < len(words)-1; i++ {
+		w := words[i]
+		if len(w) > 1 && strings.HasPrefix(w, "-") {
+			if !strings.Contains(w, "=") && takesValue(cmd, w) {
+				i++
+			}
+			continue
+		}
+		if !inOp {
+			inOp = w == "op"
+			if !inOp {
+				return nil
+			}
+			continue
+		}
+		positional = append(positional, w)
+	}
+	if !inOp || len(positional) == 0 {
+		return nil
+	}
+	if !connectOptions(words[:len(words)-1]) {
+		return nil
+	}
+	// completion must stay quiet
+	svc, err := dial(newClient(ioutil.Discard), positional[0], ioutil.Discard)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	if len(positional) == 1 {
+		for _, op := range svc.Operations() {
+			if strings.HasPrefix(op.ID, last) {
+				out = append(out, op.ID)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	op, err := findOperation(svc, positional[1])
+	if err != nil {
+		return nil
+	}
+	given := map[string]bool{}
+	for _, a := range positional[2:] {
+		given[strings.SplitN(a, "=", 2)[0]] = true
+	}
+	for _, p := range op.Params {
+		if !given[p.Name] && strings.HasPrefix(p.Name+"=", last) {
+			out = append(out, p.Name+"=")
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// connectOptions sets the global options given by words, as completion
+// does not run the command that would. It reports false if the spec would be
+// read from stdin, which holds no document while completing.
+func connectOptions(words []string) bool {
+	flags.NewParser(opts, flags.IgnoreUnknown).ParseArgs(words)
+	return opts.Spec != "-"
+}
+
+// takesValue reports whether the option w of cmd, or of the application,
+// consumes the following word as its value.
+func takesValue(cmd *flags.Command, w string) bool {
+	var opt *flags.Option
+	if strings.HasPrefix(w, "--") {
+		opt = cmd.FindOptionByLongName(w[2:])
+	} else if len(w) == 2 {
+		opt = cmd.FindOptionByShortName(rune(w[1]))
+	}
+	if opt == nil {
+		return false
+	}
+	_, isBool := opt.Value().(bool)
+	return !isBool
 }
 
 func buildParser() *flags.Parser {
@@ -130,13 +1407,30 @@ func buildParser() *flags.Parser {
 	}{
 		{&Info{}, "info", "Service Info", ""},
 		{&Collections{}, "coll", "Collection Info", ""},
-		{&Operation{}, "op", "Execute Operation", "Arguments in form of name=value"},
+		{&Items{}, "items", "Collection Items", "Properties, sorting and geometry are applied locally if the server does not support them"},
+		{&Item{}, "item", "Features by ID", "Fetches the features with the given IDs concurrently"},
+		{&Export{}, "export", "Export features to a file", "Exports the given collections, or all if none are given. The format is chosen by the file extension: .gpkg, .shp, .csv or .fgb"},
+		{&Harvest{}, "harvest", "Mirror collections to disk", "Writes each collection, or all if none are given, to <DIR>/<COLLECTION>.ndjson. Progress is saved after every page so an interrupted harvest resumes where it stopped"},
+		{&Sync{}, "sync", "Report changes since the last sync", "Compares the features of a collection with the snapshot of the previous sync and reports added, modified and removed features"},
+		{&Create{}, "create", "Create features", "Adds the GeoJSON Feature or each feature of the FeatureCollection read from FILE, or stdin if omitted, and prints the new IDs"},
+		{&Update{}, "update", "Update a feature", "Applies the JSON merge patch read from FILE, or stdin if omitted, to the feature. With --replace the input is the complete new feature"},
+		{&Delete{}, "delete", "Delete features", "Deletes the features with the given IDs in turn, stopping at the first error. With --if-match a single feature is deleted only if its ETag is unchanged"},
+		{&Shell{}, "shell", "Interactive shell", "Connects once and runs operations interactively, with completion of operation IDs and parameter names"},
+		{&Operation{}, "op", "Execute Operation", "The operation is given by ID or as \"METHOD /path\". Arguments in form of name=value"},
+		{&Gen{}, "gen", "Generate a typed Go client", "Writes a Go package with a method and parameter struct for every operation and, unless only --spec is given, a type for every collection with its feature properties"},
+		{&Serve{}, "serve", "Serve GeoJSON files as a mock service", "Serves each GeoJSON FeatureCollection file as a collection named after the file, with a generated OpenAPI document. Latency and errors can be injected to test clients"},
+		{&Completion{}, "completion", "Shell completion script", "Writes a completion script for bash, zsh or fish. Operation IDs and parameters of op are completed from the spec of the service on the command line"},
 	} {
 		_, e := parser.AddCommand(c.name, c.short, c.long, c.cmd)
 		if e != nil {
 			panic(e)
 		}
 	}
+	hook, e := parser.AddCommand("__complete", "", "", &completeHook{})
+	if e != nil {
+		panic(e)
+	}
+	hook.Hidden = true
 	return parser
 }
 
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"context"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// layerFor describes the Layer for a collection. The fields come from the
+// collection schema, or are inferred from the first page if the server does
+// not publish one.
+func layerFor(ctx context.Context, c wfs.Collection, first wfs.FeatureCollection) Layer {
+	l := Layer{Name: c.Name, Crs: first.Crs}
+	if info, err := c.Info(ctx); err == nil {
+		l.Title, l.Description = info.Title, info.Description
+	}
+	if props, err := c.Schema(ctx); err == nil && len(props) > 0 {
+		l.Fields = FieldsFromSchema(props)
+	} else {
+		l.Fields = InferFields(first.Features)
+	}
+	return l
+}
+
+// WriteCollection pages through all features of the collection matching q
+// and writes them to the FeatureWriter returned by create, which is called
+// with the Layer describing the collection once the first page is fetched.
+// The writer is closed, or discarded if writing failed, and the number of
+// features written is returned.
+func WriteCollection(ctx context.Context, c wfs.Collection, q wfs.ItemsQuery, create func(Layer) (FeatureWriter, error)) (int, error) {
+	var w FeatureWriter
+	count := 0
+	pages := c.Pages(ctx, q)
+	for pages.Next() {
+		page := pages.Page()
+		if w == nil {
+			var err error
+			if w, err = create(layerFor(ctx, c, page)); err != nil {
+				return count, err
+			}
+		}
+		if err := w.Write(page.Features); err != nil {
+			w.Discard()
+			return count, err
+		}
+		count += len(page.Features)
+	}
+	if err := pages.Err(); err != nil {
+		if w != nil {
+			w.Discard()
+		}
+		return count, err
+	}
+	if w == nil {
+		return 0, nil
+	}
+	if err := w.Close(); err != nil {
+		w.Discard()
+		return count, err
+	}
+	return count, nil
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+func TestWriteCollectionDiscard(t *testing.T) {
+	srv := pagedServer(map[string]int{})
+	defer srv.Close()
+	paged := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, "gone", http.StatusBadGateway)
+			return
+		}
+		paged.ServeHTTP(w, r)
+	})
+	svc, err := wfs.NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "discard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	for _, name := range []string{"c.csv", "c.shp", "c.fgb", "c.ndjson"} {
+		path := filepath.Join(dir, name)
+		n, err := WriteCollection(ctx, svc.Collection("c"), wfs.ItemsQuery{}, func(l Layer) (FeatureWriter, error) {
+			return Create(path, l)
+		})
+		if err == nil || n != 4 {
+			t.Errorf("%s : %d features, %v", name, n, err)
+		}
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d files left", len(files))
+	}
+
+	gp, err := CreateGeoPackage(filepath.Join(dir, "c.gpkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gp.Close()
+	_, err = WriteCollection(ctx, svc.Collection("c"), wfs.ItemsQuery{}, func(l Layer) (FeatureWriter, error) {
+		return gp.CreateLayer(l)
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+	var tables int
+	if err := gp.db.QueryRow("SELECT count(*) FROM sqlite_master WHERE name IN ('c', 'rtree_c_geom')").Scan(&tables); err != nil || tables != 0 {
+		t.Errorf("%d tables left %v", tables, err)
+	}
+	var contents int
+	gp.db.QueryRow("SELECT count(*) FROM gpkg_contents").Scan(&contents)
+	if contents != 0 {
+		t.Errorf("%d contents left", contents)
+	}
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"encoding/csv"
+	"os"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// CSV writes features as comma separated values with the geometry as WKT in
+// the first column, followed by the feature ID (unless the Layer has an "id"
+// field) and the Layer fields.
+type CSV struct {
+	f        *os.File
+	w        *csv.Writer
+	fields   []Field
+	idColumn bool
+}
+
+// CreateCSV creates the file at path and writes the header row.
+func CreateCSV(path string, l Layer) (*CSV, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &CSV{f, csv.NewWriter(f), l.Fields, !l.hasField("id")}
+	header := []string{"WKT"}
+	if c.idColumn {
+		header = append(header, "id")
+	}
+	for _, fd := range l.Fields {
+		header = append(header, fd.Name)
+	}
+	if err := c.w.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Write implements FeatureWriter.
+func (c *CSV) Write(features []wfs.Feature) error {
+	for _, f := range features {
+		row := make([]string, 0, len(c.fields)+2)
+		wkt := ""
+		if f.Geometry != nil {
+			var err error
+			if wkt, err = WKT(f.Geometry); err != nil {
+				return err
+			}
+		}
+		row = append(row, wkt)
+		if c.idColumn {
+			row = append(row, featureID(f))
+		}
+		for _, fd := range c.fields {
+			row = append(row, formatValue(fieldValue(fd.Type, f.Properties[fd.Name])))
+		}
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close implements FeatureWriter.
+func (c *CSV) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		c.f.Close()
+		return err
+	}
+	return c.f.Close()
+}
+
+// Discard implements FeatureWriter.
+func (c *CSV) Discard() error {
+	c.f.Close()
+	return os.Remove(c.f.Name())
+}
@@ -0,0 +1,37 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.csv")
+	features := testFeatures()
+	w, err := Create(path, Layer{Fields: InferFields(features)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(features); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadFile(path)
+	expected := `WKT,id,name,pop,tags
+POINT (1 2),a,first,10,
+"POLYGON ((0 0, 4 0, 4 3, 0 0))",2,second,,"[""x""]"
+,,third,,
+`
+	if string(b) != expected {
+		t.Errorf("got\n%s", b)
+	}
+}
@@ -0,0 +1,259 @@
+// Package export writes features fetched through the wfs client to GIS
+// interchange formats. Geometries are written in two dimensions; any z
+// values are dropped.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// FeatureWriter writes the features of a single Layer. Close must be called
+// to complete the output, or Discard to remove it when writing failed, so no
+// truncated output is left behind. Discard may follow a failed Close.
+type FeatureWriter interface {
+	Write(features []wfs.Feature) error
+	Close() error
+	Discard() error
+}
+
+// Warner is implemented by FeatureWriters that report lossy conversions, such
+// as truncated field names or values.
+type Warner interface {
+	Warnings() []string
+}
+
+// Create creates a FeatureWriter for l at path, choosing the format by the
+// file extension: ".shp" for ESRI Shapefile, ".csv" for CSV with WKT
+// geometries, ".fgb" for FlatGeobuf, ".ndjson" or ".geojsonl" for
+// newline-delimited GeoJSON and ".geojsons" for GeoJSON text sequences. Use
+// CreateGeoPackage for ".gpkg".
+func Create(path string, l Layer) (FeatureWriter, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".shp":
+		s, err := CreateShapefile(path, l)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case ".csv":
+		c, err := CreateCSV(path, l)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case ".fgb":
+		f, err := CreateFlatGeobuf(path, l)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case ".ndjson", ".geojsonl", ".geojsons":
+		s, err := CreateGeoJSONSeq(path, strings.ToLower(filepath.Ext(path)) == ".geojsons")
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", filepath.Ext(path))
+}
+
+// warnings collects distinct warning messages.
+type warnings struct {
+	seen     map[string]bool
+	messages []string
+}
+
+func (w *warnings) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if w.seen == nil {
+		w.seen = map[string]bool{}
+	}
+	if !w.seen[msg] {
+		w.seen[msg] = true
+		w.messages = append(w.messages, msg)
+	}
+}
+
+// Warnings implements Warner.
+func (w *warnings) Warnings() []string {
+	return w.messages
+}
+
+// FieldType is the type of an attribute column.
+type FieldType int
+
+// Supported field types.
+const (
+	String FieldType = iota
+	Integer
+	Real
+	Boolean
+	Date
+	DateTime
+	// JSON holds object and array values encoded as JSON text.
+	JSON
+)
+
+// Field is a single attribute column of a Layer.
+type Field struct {
+	Name string
+	Type FieldType
+}
+
+// Layer describes a table of features to be written.
+type Layer struct {
+	Name        string
+	Title       string
+	Description string
+	// Crs identifies the CRS of the geometries, CRS84 if empty.
+	Crs    string
+	Fields []Field
+}
+
+// FieldsFromSchema maps collection schema properties to Fields.
+func FieldsFromSchema(props []wfs.Property) []Field {
+	fields := make([]Field, 0, len(props))
+	for _, p := range props {
+		t := String
+		switch p.Type {
+		case "integer":
+			t = Integer
+		case "number":
+			t = Real
+		case "boolean":
+			t = Boolean
+		case "object", "array":
+			t = JSON
+		case "string":
+			switch p.Format {
+			case "date":
+				t = Date
+			case "date-time":
+				t = DateTime
+			}
+		}
+		fields = append(fields, Field{p.Name, t})
+	}
+	return fields
+}
+
+// InferFields derives Fields from the property values of features, for
+// collections that do not publish a schema. The type of a property is taken
+// from its first non-null value; numbers are Real unless every value is
+// integral.
+func InferFields(features []wfs.Feature) []Field {
+	types := map[string]FieldType{}
+	seen := map[string]bool{}
+	for _, f := range features {
+		for k, v := range f.Properties {
+			if v == nil {
+				if _, ok := types[k]; !ok {
+					types[k] = String
+				}
+				continue
+			}
+			t := valueType(v)
+			if !seen[k] {
+				types[k] = t
+				seen[k] = true
+			} else if types[k] == Integer && t == Real {
+				types[k] = Real
+			}
+		}
+	}
+	fields := make([]Field, 0, len(types))
+	for k, t := range types {
+		fields = append(fields, Field{k, t})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+func valueType(v interface{}) FieldType {
+	switch t := v.(type) {
+	case bool:
+		return Boolean
+	case float64:
+		if t == float64(int64(t)) {
+			return Integer
+		}
+		return Real
+	case map[string]interface{}, []interface{}:
+		return JSON
+	}
+	return String
+}
+
+// fieldValue converts a decoded JSON property value to the Go value stored
+// for a Field of type t. nil is returned for missing values.
+func fieldValue(t FieldType, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch t {
+	case Integer:
+		if f, ok := v.(float64); ok {
+			return int64(f)
+		}
+	case Real:
+		if f, ok := v.(float64); ok {
+			return f
+		}
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case JSON:
+		b, err := json.Marshal(v)
+		if err == nil {
+			return string(b)
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// formatValue returns the text form of a value returned by fieldValue.
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	return fmt.Sprint(v)
+}
+
+// hasField reports whether l has a field with the given name.
+func (l Layer) hasField(name string) bool {
+	for _, f := range l.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// featureID returns the string form of a feature ID, or "" if absent.
+func featureID(f wfs.Feature) string {
+	if f.ID == nil {
+		return ""
+	}
+	if n, ok := f.ID.(float64); ok && n == float64(int64(n)) {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return fmt.Sprint(f.ID)
+}
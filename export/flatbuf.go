@@ -0,0 +1,225 @@
+package export
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// fbObject is a FlatBuffers object that can be serialized by fbBuilder.
+type fbObject interface {
+	// write appends the object and returns the position that references to
+	// it point to.
+	write(b *fbBuilder) int
+}
+
+// fbBuilder serializes FlatBuffers front to back: each table is followed by
+// the objects it references, so all offsets point forward as required.
+// Positions and alignment are relative to the start of buf.
+type fbBuilder struct {
+	buf []byte
+}
+
+func (b *fbBuilder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// padBefore pads so that after writing n more bytes the position is aligned.
+func (b *fbBuilder) padBefore(n, align int) {
+	for (len(b.buf)+n)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) uint32At(pos int, v uint32) {
+	binary.LittleEndian.PutUint32(b.buf[pos:], v)
+}
+
+func (b *fbBuilder) appendUint32(v uint32) {
+	var x [4]byte
+	binary.LittleEndian.PutUint32(x[:], v)
+	b.buf = append(b.buf, x[:]...)
+}
+
+// finish serializes root as a size prefixed buffer.
+func (b *fbBuilder) finish(root fbObject) []byte {
+	b.buf = b.buf[:0]
+	b.appendUint32(0) // size prefix
+	b.appendUint32(0) // root offset
+	pos := root.write(b)
+	b.uint32At(4, uint32(pos-4))
+	b.pad(8)
+	b.uint32At(0, uint32(len(b.buf)-4))
+	return b.buf
+}
+
+// fbField is a table field: either an inline scalar or a reference.
+type fbField struct {
+	id     int
+	scalar []byte
+	ref    fbObject
+}
+
+type fbTable struct {
+	fields []fbField
+}
+
+func (t *fbTable) add(id int, scalar []byte) {
+	t.fields = append(t.fields, fbField{id: id, scalar: scalar})
+}
+
+func (t *fbTable) ref(id int, o fbObject) {
+	if o != nil {
+		t.fields = append(t.fields, fbField{id: id, ref: o})
+	}
+}
+
+func (t *fbTable) uint8(id int, v uint8) { t.add(id, []byte{v}) }
+
+func (t *fbTable) uint16(id int, v uint16) {
+	var x [2]byte
+	binary.LittleEndian.PutUint16(x[:], v)
+	t.add(id, x[:])
+}
+
+func (t *fbTable) int32(id int, v int32) {
+	var x [4]byte
+	binary.LittleEndian.PutUint32(x[:], uint32(v))
+	t.add(id, x[:])
+}
+
+func (t *fbTable) uint64(id int, v uint64) {
+	var x [8]byte
+	binary.LittleEndian.PutUint64(x[:], v)
+	t.add(id, x[:])
+}
+
+func (t *fbTable) string(id int, s string) {
+	if s != "" {
+		t.ref(id, fbString(s))
+	}
+}
+
+func (t *fbTable) write(b *fbBuilder) int {
+	fields := append([]fbField(nil), t.fields...)
+	// larger fields first keeps the padding small
+	sort.SliceStable(fields, func(i, j int) bool { return fieldSize(fields[i]) > fieldSize(fields[j]) })
+	maxID, align := -1, 4
+	for _, f := range fields {
+		if f.id > maxID {
+			maxID = f.id
+		}
+		if s := fieldSize(f); s > align {
+			align = s
+		}
+	}
+	// lay out the table relative to its aligned start
+	offsets := make([]int, len(fields))
+	size := 4
+	for i, f := range fields {
+		s := fieldSize(f)
+		for size%s != 0 {
+			size++
+		}
+		offsets[i] = size
+		size += s
+	}
+	vt := make([]byte, 4+2*(maxID+1))
+	binary.LittleEndian.PutUint16(vt, uint16(len(vt)))
+	binary.LittleEndian.PutUint16(vt[2:], uint16(size))
+	for i, f := range fields {
+		binary.LittleEndian.PutUint16(vt[4+2*f.id:], uint16(offsets[i]))
+	}
+	b.pad(2)
+	vtPos := len(b.buf)
+	b.buf = append(b.buf, vt...)
+	b.pad(align)
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(int32(pos-vtPos)))
+	for i, f := range fields {
+		if f.ref == nil {
+			copy(b.buf[pos+offsets[i]:], f.scalar)
+		}
+	}
+	for i, f := range fields {
+		if f.ref != nil {
+			at := pos + offsets[i]
+			b.uint32At(at, uint32(f.ref.write(b)-at))
+		}
+	}
+	return pos
+}
+
+func fieldSize(f fbField) int {
+	if f.ref != nil {
+		return 4
+	}
+	return len(f.scalar)
+}
+
+type fbString string
+
+func (s fbString) write(b *fbBuilder) int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return pos
+}
+
+// fbBytes is a vector of ubyte.
+type fbBytes []byte
+
+func (v fbBytes) write(b *fbBuilder) int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	b.buf = append(b.buf, v...)
+	return pos
+}
+
+type fbUint32s []uint32
+
+func (v fbUint32s) write(b *fbBuilder) int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	for _, x := range v {
+		b.appendUint32(x)
+	}
+	return pos
+}
+
+type fbFloat64s []float64
+
+func (v fbFloat64s) write(b *fbBuilder) int {
+	b.padBefore(4, 8)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	var x [8]byte
+	for _, f := range v {
+		binary.LittleEndian.PutUint64(x[:], math.Float64bits(f))
+		b.buf = append(b.buf, x[:]...)
+	}
+	return pos
+}
+
+// fbTables is a vector of tables.
+type fbTables []*fbTable
+
+func (v fbTables) write(b *fbBuilder) int {
+	b.pad(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	start := len(b.buf)
+	b.buf = append(b.buf, make([]byte, 4*len(v))...)
+	for i, t := range v {
+		at := start + 4*i
+		b.uint32At(at, uint32(t.write(b)-at))
+	}
+	return pos
+}
@@ -0,0 +1,500 @@
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/ischneider/go-wfs-client/proj"
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+var fgbMagic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b', 0}
+
+// fgbNodeSize is the branching factor of the spatial index.
+const fgbNodeSize = 16
+
+// FlatGeobuf geometry types.
+var fgbGeometryTypes = map[string]uint8{
+	"Point":              1,
+	"LineString":         2,
+	"Polygon":            3,
+	"MultiPoint":         4,
+	"MultiLineString":    5,
+	"MultiPolygon":       6,
+	"GeometryCollection": 7,
+}
+
+// FlatGeobuf column types.
+const (
+	fgbBool     = 2
+	fgbLong     = 7
+	fgbDouble   = 10
+	fgbString   = 11
+	fgbJSON     = 12
+	fgbDateTime = 13
+)
+
+// fgbFeature locates an encoded feature in the temporary file.
+type fgbFeature struct {
+	offset, size int64
+	bounds       wfs.BBox
+	hasGeometry  bool
+	hilbert      uint32
+}
+
+// FlatGeobuf writes a FlatGeobuf file with a packed Hilbert R-tree index.
+// Since the header and index precede the features, features are buffered in
+// a temporary file and the output is written on Close. The index is omitted
+// if any feature has no geometry.
+type FlatGeobuf struct {
+	warnings
+	path     string
+	layer    Layer
+	tmp      *os.File
+	tmpW     *bufio.Writer
+	size     int64
+	idField  bool
+	features []fgbFeature
+	geomType string
+	mixed    bool
+	fb       fbBuilder
+}
+
+// CreateFlatGeobuf prepares writing l to path.
+func CreateFlatGeobuf(path string, l Layer) (*FlatGeobuf, error) {
+	tmp, err := ioutil.TempFile("", "fgb")
+	if err != nil {
+		return nil, err
+	}
+	w := &FlatGeobuf{path: path, layer: l, tmp: tmp, tmpW: bufio.NewWriter(tmp), idField: !l.hasField("id")}
+	if w.idField {
+		w.layer.Fields = append([]Field{{"id", String}}, l.Fields...)
+	}
+	return w, nil
+}
+
+// Write implements FeatureWriter.
+func (w *FlatGeobuf) Write(features []wfs.Feature) error {
+	for _, f := range features {
+		t := &fbTable{}
+		ff := fgbFeature{offset: w.size}
+		if f.Geometry != nil {
+			g, err := fgbGeometry(f.Geometry)
+			if err != nil {
+				return err
+			}
+			t.ref(0, g)
+			ff.bounds, ff.hasGeometry = f.Geometry.Bounds()
+			if w.geomType == "" {
+				w.geomType = f.Geometry.Type
+			} else if w.geomType != f.Geometry.Type {
+				w.mixed = true
+			}
+		}
+		if props := w.properties(f); len(props) > 0 {
+			t.ref(1, fbBytes(props))
+		}
+		buf := w.fb.finish(t)
+		if _, err := w.tmpW.Write(buf); err != nil {
+			return err
+		}
+		ff.size = int64(len(buf))
+		w.size += ff.size
+		w.features = append(w.features, ff)
+	}
+	return nil
+}
+
+// properties encodes the property values as column index and value pairs.
+func (w *FlatGeobuf) properties(f wfs.Feature) []byte {
+	var out []byte
+	var b [8]byte
+	for i, fd := range w.layer.Fields {
+		var v interface{}
+		if w.idField && i == 0 {
+			if id := featureID(f); id != "" {
+				v = id
+			}
+		} else {
+			v = fieldValue(fd.Type, f.Properties[fd.Name])
+		}
+		if v == nil {
+			continue
+		}
+		if !fgbMatches(fd.Type, v) {
+			w.warn("values of %q not matching the field type skipped", fd.Name)
+			continue
+		}
+		binary.LittleEndian.PutUint16(b[:], uint16(i))
+		out = append(out, b[:2]...)
+		switch t := v.(type) {
+		case bool:
+			if t {
+				out = append(out, 1)
+			} else {
+				out = append(out, 0)
+			}
+		case int64:
+			binary.LittleEndian.PutUint64(b[:], uint64(t))
+			out = append(out, b[:]...)
+		case float64:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(t))
+			out = append(out, b[:]...)
+		default:
+			s := formatValue(v)
+			binary.LittleEndian.PutUint32(b[:], uint32(len(s)))
+			out = append(out, b[:4]...)
+			out = append(out, s...)
+		}
+	}
+	return out
+}
+
+func fgbGeometry(g *wfs.Geometry) (*fbTable, error) {
+	gt, ok := fgbGeometryTypes[g.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	t := &fbTable{}
+	t.uint8(6, gt)
+	var xy fbFloat64s
+	var ends fbUint32s
+	add := func(ps ...wfs.Position) {
+		for _, p := range ps {
+			if len(p) < 2 {
+				xy = append(xy, math.NaN(), math.NaN())
+				continue
+			}
+			xy = append(xy, p[0], p[1])
+		}
+	}
+	parts := func(lines [][]wfs.Position) {
+		for _, l := range lines {
+			add(l...)
+			ends = append(ends, uint32(len(xy)/2))
+		}
+	}
+	switch g.Type {
+	case "Point":
+		add(g.Point)
+	case "MultiPoint":
+		add(g.MultiPoint...)
+	case "LineString":
+		add(g.LineString...)
+	case "MultiLineString":
+		parts(g.MultiLineString)
+	case "Polygon":
+		parts(g.Polygon)
+	case "MultiPolygon", "GeometryCollection":
+		var children fbTables
+		geoms := g.Geometries
+		if g.Type == "MultiPolygon" {
+			geoms = nil
+			for _, p := range g.MultiPolygon {
+				geoms = append(geoms, &wfs.Geometry{Type: "Polygon", Polygon: p})
+			}
+		}
+		for _, c := range geoms {
+			ct, err := fgbGeometry(c)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, ct)
+		}
+		t.ref(7, children)
+	}
+	if len(ends) > 1 {
+		t.ref(0, ends)
+	}
+	if len(xy) > 0 {
+		t.ref(1, xy)
+	}
+	return t, nil
+}
+
+// header builds the header table.
+func (w *FlatGeobuf) header(extent wfs.BBox, empty, indexed bool) *fbTable {
+	h := &fbTable{}
+	h.string(0, w.layer.Name)
+	if !empty {
+		h.ref(1, fbFloat64s(extent[:]))
+	}
+	if !w.mixed {
+		h.uint8(2, fgbGeometryTypes[w.geomType])
+	}
+	var cols fbTables
+	for _, f := range w.layer.Fields {
+		c := &fbTable{}
+		c.string(0, f.Name)
+		c.uint8(1, fgbColumnType(f.Type))
+		cols = append(cols, c)
+	}
+	if len(cols) > 0 {
+		h.ref(7, cols)
+	}
+	h.uint64(8, uint64(len(w.features)))
+	nodeSize := uint16(fgbNodeSize)
+	if !indexed {
+		nodeSize = 0
+	}
+	h.uint16(9, nodeSize)
+	h.ref(10, fgbCrs(w.layer.Crs))
+	h.string(11, w.layer.Title)
+	h.string(12, w.layer.Description)
+	return h
+}
+
+func fgbColumnType(t FieldType) uint8 {
+	switch t {
+	case Integer:
+		return fgbLong
+	case Real:
+		return fgbDouble
+	case Boolean:
+		return fgbBool
+	case Date, DateTime:
+		return fgbDateTime
+	case JSON:
+		return fgbJSON
+	}
+	return fgbString
+}
+
+// fgbMatches reports whether v, as returned by fieldValue, is encoded as the
+// column type of t.
+func fgbMatches(t FieldType, v interface{}) bool {
+	var ok bool
+	switch fgbColumnType(t) {
+	case fgbLong:
+		_, ok = v.(int64)
+	case fgbDouble:
+		_, ok = v.(float64)
+	case fgbBool:
+		_, ok = v.(bool)
+	default:
+		_, ok = v.(string)
+	}
+	return ok
+}
+
+func fgbCrs(crs string) fbObject {
+	if crs == "" {
+		crs = wfs.CRS84
+	}
+	code, ok := wfs.EPSGCode(crs)
+	if !ok {
+		return nil
+	}
+	t := &fbTable{}
+	t.string(0, "EPSG")
+	t.int32(1, int32(code))
+	if c, err := proj.Lookup(code); err == nil {
+		t.string(2, c.Name)
+		if wkt, err := c.WKT(); err == nil {
+			t.string(4, wkt)
+		}
+	}
+	return t
+}
+
+// Close writes the header, the index and the features to the output file.
+func (w *FlatGeobuf) Close() error {
+	defer os.Remove(w.tmp.Name())
+	defer w.tmp.Close()
+	if err := w.tmpW.Flush(); err != nil {
+		return err
+	}
+	indexed := len(w.features) > 0
+	var extent wfs.BBox
+	empty := true
+	for _, f := range w.features {
+		if !f.hasGeometry {
+			if indexed {
+				w.warn("features without geometry, spatial index omitted")
+			}
+			indexed = false
+			continue
+		}
+		if empty {
+			extent, empty = f.bounds, false
+			continue
+		}
+		extent = wfs.BBox{
+			math.Min(extent[0], f.bounds[0]), math.Min(extent[1], f.bounds[1]),
+			math.Max(extent[2], f.bounds[2]), math.Max(extent[3], f.bounds[3]),
+		}
+	}
+	if indexed {
+		hilbertSort(w.features, extent)
+	}
+
+	out, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(out)
+	bw.Write(fgbMagic)
+	bw.Write(w.fb.finish(w.header(extent, empty, indexed)))
+	if indexed {
+		if err := writeIndex(bw, w.features); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	for _, f := range w.features {
+		if _, err := io.Copy(bw, io.NewSectionReader(w.tmp, f.offset, f.size)); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Discard implements FeatureWriter.
+func (w *FlatGeobuf) Discard() error {
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+	if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// hilbertSort orders the features by the Hilbert value of the center of
+// their bounds in the extent, as the FlatGeobuf reference implementation.
+func hilbertSort(features []fgbFeature, extent wfs.BBox) {
+	const max = (1 << 16) - 1
+	width, height := extent[2]-extent[0], extent[3]-extent[1]
+	for i := range features {
+		b := features[i].bounds
+		var x, y uint32
+		if width > 0 {
+			x = uint32(math.Floor(max * ((b[0]+b[2])/2 - extent[0]) / width))
+		}
+		if height > 0 {
+			y = uint32(math.Floor(max * ((b[1]+b[3])/2 - extent[1]) / height))
+		}
+		features[i].hilbert = hilbert(x, y)
+	}
+	sort.SliceStable(features, func(i, j int) bool { return features[i].hilbert > features[j].hilbert })
+}
+
+// hilbert returns the index of x, y on a 16 bit Hilbert curve.
+func hilbert(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+	return (i1 << 1) | i0
+}
+
+// levelBounds returns the node index ranges of each tree level, leaves first.
+func levelBounds(items, nodeSize int) [][2]int {
+	n := items
+	nodes := n
+	counts := []int{n}
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		nodes += n
+		counts = append(counts, n)
+		if n == 1 {
+			break
+		}
+	}
+	bounds := make([][2]int, len(counts))
+	n = nodes
+	for i, c := range counts {
+		bounds[i] = [2]int{n - c, n}
+		n -= c
+	}
+	return bounds
+}
+
+type fgbNode struct {
+	bounds wfs.BBox
+	offset uint64
+}
+
+// writeIndex writes the packed R-tree over the sorted features. Leaf nodes
+// hold the byte offset of the feature, other nodes the index of their first
+// child.
+func writeIndex(w io.Writer, features []fgbFeature) error {
+	levels := levelBounds(len(features), fgbNodeSize)
+	nodes := make([]fgbNode, levels[0][1])
+	var offset uint64
+	for i, f := range features {
+		nodes[levels[0][0]+i] = fgbNode{f.bounds, offset}
+		offset += uint64(f.size)
+	}
+	for l := 0; l < len(levels)-1; l++ {
+		parent := levels[l+1][0]
+		for pos := levels[l][0]; pos < levels[l][1]; pos += fgbNodeSize {
+			n := fgbNode{nodes[pos].bounds, uint64(pos)}
+			for c := pos + 1; c < pos+fgbNodeSize && c < levels[l][1]; c++ {
+				b := nodes[c].bounds
+				n.bounds = wfs.BBox{
+					math.Min(n.bounds[0], b[0]), math.Min(n.bounds[1], b[1]),
+					math.Max(n.bounds[2], b[2]), math.Max(n.bounds[3], b[3]),
+				}
+			}
+			nodes[parent] = n
+			parent++
+		}
+	}
+	buf := make([]byte, 40)
+	for _, n := range nodes {
+		for i, v := range n.bounds {
+			binary.LittleEndian.PutUint64(buf[8*i:], math.Float64bits(v))
+		}
+		binary.LittleEndian.PutUint64(buf[32:], n.offset)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
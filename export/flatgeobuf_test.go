@@ -0,0 +1,150 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// fbFieldPos returns the position of field id of the table at pos, or 0.
+func fbFieldPos(buf []byte, pos, id int) int {
+	vt := pos - int(int32(binary.LittleEndian.Uint32(buf[pos:])))
+	if 4+2*id >= int(binary.LittleEndian.Uint16(buf[vt:])) {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(buf[vt+4+2*id:]))
+	if off == 0 {
+		return 0
+	}
+	return pos + off
+}
+
+func fbStringAt(buf []byte, pos int) string {
+	pos += int(binary.LittleEndian.Uint32(buf[pos:]))
+	n := int(binary.LittleEndian.Uint32(buf[pos:]))
+	return string(buf[pos+4 : pos+4+n])
+}
+
+func TestFlatGeobuf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fgb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.fgb")
+	var features []wfs.Feature
+	for i := 0; i < 20; i++ {
+		features = append(features, wfs.Feature{ID: float64(i),
+			Geometry:   &wfs.Geometry{Type: "Point", Point: wfs.Position{float64(i), float64(i % 5)}},
+			Properties: map[string]interface{}{"n": float64(i)}})
+	}
+	w, err := CreateFlatGeobuf(path, Layer{Name: "points", Fields: InferFields(features)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(features); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadFile(path)
+	if !bytes.Equal(b[:8], fgbMagic) {
+		t.Fatalf("magic %v", b[:8])
+	}
+	hdr := b[8:]
+	size := int(binary.LittleEndian.Uint32(hdr))
+	root := 4 + int(binary.LittleEndian.Uint32(hdr[4:]))
+	if name := fbStringAt(hdr, fbFieldPos(hdr, root, 0)); name != "points" {
+		t.Errorf("name %q", name)
+	}
+	if gt := hdr[fbFieldPos(hdr, root, 2)]; gt != 1 {
+		t.Errorf("geometry type %d", gt)
+	}
+	if n := binary.LittleEndian.Uint64(hdr[fbFieldPos(hdr, root, 8):]); n != 20 {
+		t.Errorf("features count %d", n)
+	}
+	if n := binary.LittleEndian.Uint16(hdr[fbFieldPos(hdr, root, 9):]); n != fgbNodeSize {
+		t.Errorf("node size %d", n)
+	}
+	// 20 leaves, 2 inner nodes and the root
+	index := 8 + 4 + size
+	features0 := index + 23*40
+	root0 := b[index:]
+	if first := binary.LittleEndian.Uint64(root0[32:]); first != 1 {
+		t.Errorf("root first child %d", first)
+	}
+	leaf := b[index+3*40:]
+	if off := binary.LittleEndian.Uint64(leaf[32:]); off != 0 {
+		t.Errorf("first leaf offset %d", off)
+	}
+	var total int
+	for pos := features0; pos < len(b); {
+		pos += 4 + int(binary.LittleEndian.Uint32(b[pos:]))
+		total++
+	}
+	if total != 20 {
+		t.Errorf("features %d", total)
+	}
+}
+
+func TestFlatGeobufNoIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fgb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := CreateFlatGeobuf(filepath.Join(dir, "out.fgb"), Layer{Name: "places"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(testFeatures()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Warnings()) != 1 {
+		t.Errorf("warnings %q", w.Warnings())
+	}
+}
+
+func TestFlatGeobufMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fgb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := CreateFlatGeobuf(filepath.Join(dir, "out.fgb"), Layer{Name: "places", Fields: []Field{{"pop", Integer}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	features := []wfs.Feature{
+		{Geometry: &wfs.Geometry{Type: "Point", Point: wfs.Position{1, 2}}, Properties: map[string]interface{}{"pop": "many"}},
+	}
+	if err := w.Write(features); err != nil {
+		t.Fatal(err)
+	}
+	if props := w.properties(features[0]); len(props) != 0 {
+		t.Errorf("properties %v", props)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Warnings()) != 1 || !strings.Contains(w.Warnings()[0], "pop") {
+		t.Errorf("warnings %q", w.Warnings())
+	}
+}
+
+func TestLevelBounds(t *testing.T) {
+	lb := levelBounds(20, 16)
+	if len(lb) != 3 || lb[0] != [2]int{3, 23} || lb[1] != [2]int{1, 3} || lb[2] != [2]int{0, 1} {
+		t.Errorf("bounds %v", lb)
+	}
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// recordSeparator starts each text of a GeoJSON text sequence (RFC 8142).
+const recordSeparator = 0x1E
+
+// GeoJSONSeq writes one GeoJSON feature per line, either as newline-delimited
+// JSON or, with record separators, as a GeoJSON text sequence.
+type GeoJSONSeq struct {
+	w    *bufio.Writer
+	c    io.Closer
+	path string
+	enc  *json.Encoder
+	rs   bool
+}
+
+// NewGeoJSONSeq returns a GeoJSONSeq writing to w. If rs is set, each feature
+// is prefixed with the ASCII record separator as RFC 8142 requires. Close
+// flushes the output but does not close w.
+func NewGeoJSONSeq(w io.Writer, rs bool) *GeoJSONSeq {
+	bw := bufio.NewWriter(w)
+	return &GeoJSONSeq{w: bw, enc: json.NewEncoder(bw), rs: rs}
+}
+
+// CreateGeoJSONSeq creates the file at path for a GeoJSONSeq.
+func CreateGeoJSONSeq(path string, rs bool) (*GeoJSONSeq, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s := NewGeoJSONSeq(f, rs)
+	s.c, s.path = f, path
+	return s, nil
+}
+
+// Write implements FeatureWriter.
+func (s *GeoJSONSeq) Write(features []wfs.Feature) error {
+	for i := range features {
+		if s.rs {
+			s.w.WriteByte(recordSeparator)
+		}
+		// Encode terminates each feature with a newline
+		if err := s.enc.Encode(&features[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered features to the underlying writer.
+func (s *GeoJSONSeq) Flush() error {
+	return s.w.Flush()
+}
+
+// Close implements FeatureWriter.
+func (s *GeoJSONSeq) Close() error {
+	err := s.w.Flush()
+	if s.c != nil {
+		if cerr := s.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Discard implements FeatureWriter. The file created by CreateGeoJSONSeq is
+// removed, while the writer given to NewGeoJSONSeq is left as is.
+func (s *GeoJSONSeq) Discard() error {
+	if s.c == nil {
+		return nil
+	}
+	s.c.Close()
+	return os.Remove(s.path)
+}
+
+// WritePages writes the features of every page to w as they are fetched,
+// returning the number of features written. w is not closed.
+func WritePages(w FeatureWriter, pages *wfs.Pages) (int, error) {
+	count := 0
+	for pages.Next() {
+		features := pages.Page().Features
+		if err := w.Write(features); err != nil {
+			return count, err
+		}
+		count += len(features)
+		if f, ok := w.(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, pages.Err()
+}
@@ -0,0 +1,26 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGeoJSONSeq(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewGeoJSONSeq(&buf, true)
+	if err := w.Write(testFeatures()[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("lines %q", buf.String())
+	}
+	for _, l := range lines {
+		if l[0] != recordSeparator || l[1] != '{' {
+			t.Errorf("line %q", l)
+		}
+	}
+}
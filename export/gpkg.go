@@ -0,0 +1,448 @@
+package export
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/ischneider/go-wfs-client/proj"
+	"github.com/ischneider/go-wfs-client/wfs"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// gpkgDriver is the go-sqlite3 driver registered with the ST_* functions the
+// R-tree triggers of the GeoPackage spec rely on.
+const gpkgDriver = "sqlite3_gpkg"
+
+func init() {
+	sql.Register(gpkgDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for name, fn := range map[string]interface{}{
+				"ST_IsEmpty": stIsEmpty,
+				"ST_MinX":    stEnvelope(0),
+				"ST_MaxX":    stEnvelope(1),
+				"ST_MinY":    stEnvelope(2),
+				"ST_MaxY":    stEnvelope(3),
+			} {
+				if err := conn.RegisterFunc(name, fn, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// gpkgEnvelope reads the minx, maxx, miny, maxy envelope of a GeoPackage
+// geometry blob header.
+func gpkgEnvelope(b []byte) (env [4]float64, empty bool, ok bool) {
+	if len(b) < 8 || b[0] != 'G' || b[1] != 'P' {
+		return env, false, false
+	}
+	flags := b[3]
+	empty = flags&0x10 != 0
+	if (flags>>1)&0x07 == 0 || len(b) < 40 {
+		return env, empty, false
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if flags&0x01 != 0 {
+		order = binary.LittleEndian
+	}
+	for i := range env {
+		env[i] = math.Float64frombits(order.Uint64(b[8+i*8:]))
+	}
+	return env, empty, true
+}
+
+// stIsEmpty and stEnvelope return concrete types, the only ones the vendored
+// go-sqlite3 converts, so a blob they cannot read is an error rather than
+// NULL. The triggers only call them on non-NULL geometries.
+func stIsEmpty(v interface{}) (int64, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return 0, fmt.Errorf("not a geometry blob")
+	}
+	if _, empty, _ := gpkgEnvelope(b); empty {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func stEnvelope(i int) func(interface{}) (float64, error) {
+	return func(v interface{}) (float64, error) {
+		b, ok := v.([]byte)
+		if !ok {
+			return 0, fmt.Errorf("not a geometry blob")
+		}
+		env, _, ok := gpkgEnvelope(b)
+		if !ok {
+			return 0, fmt.Errorf("geometry has no envelope")
+		}
+		return env[i], nil
+	}
+}
+
+// gpkgGeometry encodes g as a GeoPackage geometry blob: the standard header
+// with an xy envelope followed by WKB.
+func gpkgGeometry(g *wfs.Geometry, srsID int) ([]byte, error) {
+	w := &wkbWriter{}
+	w.buf.Write([]byte{'G', 'P', 0})
+	b, ok := g.Bounds()
+	if !ok {
+		// little endian, no envelope, empty
+		w.buf.WriteByte(0x11)
+		w.uint32(uint32(int32(srsID)))
+	} else {
+		// little endian, xy envelope
+		w.buf.WriteByte(0x03)
+		w.uint32(uint32(int32(srsID)))
+		for _, v := range []float64{b[0], b[2], b[1], b[3]} {
+			w.float64(v)
+		}
+	}
+	if err := w.geometry(g); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+// quote returns s as a quoted SQL identifier.
+func quote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+var gpkgSQLTypes = map[FieldType]string{
+	String:   "TEXT",
+	Integer:  "INTEGER",
+	Real:     "DOUBLE",
+	Boolean:  "BOOLEAN",
+	Date:     "DATE",
+	DateTime: "DATETIME",
+	JSON:     "TEXT",
+}
+
+const gpkgSchema = `
+CREATE TABLE gpkg_spatial_ref_sys (
+  srs_name TEXT NOT NULL,
+  srs_id INTEGER NOT NULL PRIMARY KEY,
+  organization TEXT NOT NULL,
+  organization_coordsys_id INTEGER NOT NULL,
+  definition TEXT NOT NULL,
+  description TEXT
+);
+CREATE TABLE gpkg_contents (
+  table_name TEXT NOT NULL PRIMARY KEY,
+  data_type TEXT NOT NULL,
+  identifier TEXT UNIQUE,
+  description TEXT DEFAULT '',
+  last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  min_x DOUBLE,
+  min_y DOUBLE,
+  max_x DOUBLE,
+  max_y DOUBLE,
+  srs_id INTEGER,
+  CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id)
+);
+CREATE TABLE gpkg_geometry_columns (
+  table_name TEXT NOT NULL,
+  column_name TEXT NOT NULL,
+  geometry_type_name TEXT NOT NULL,
+  srs_id INTEGER NOT NULL,
+  z TINYINT NOT NULL,
+  m TINYINT NOT NULL,
+  CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name),
+  CONSTRAINT uk_gc_table_name UNIQUE (table_name),
+  CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+  CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id)
+);
+CREATE TABLE gpkg_extensions (
+  table_name TEXT,
+  column_name TEXT,
+  extension_name TEXT NOT NULL,
+  definition TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  CONSTRAINT ge_tce UNIQUE (table_name, column_name, extension_name)
+);
+INSERT INTO gpkg_spatial_ref_sys VALUES
+  ('Undefined cartesian SRS', -1, 'NONE', -1, 'undefined', 'undefined cartesian coordinate reference system'),
+  ('Undefined geographic SRS', 0, 'NONE', 0, 'undefined', 'undefined geographic coordinate reference system');
+`
+
+// rtreeTriggers are the triggers of the GeoPackage R-tree spatial index
+// extension. %[1]s is the table, %[2]s the R-tree, %[3]s the geometry column
+// and %[4]s to %[9]s the trigger names.
+const rtreeTriggers = `
+CREATE TRIGGER %[4]s AFTER INSERT ON %[1]s
+  WHEN (NEW.%[3]s NOT NULL AND NOT ST_IsEmpty(NEW.%[3]s))
+BEGIN
+  INSERT OR REPLACE INTO %[2]s VALUES (NEW.fid,
+    ST_MinX(NEW.%[3]s), ST_MaxX(NEW.%[3]s), ST_MinY(NEW.%[3]s), ST_MaxY(NEW.%[3]s));
+END;
+CREATE TRIGGER %[5]s AFTER UPDATE OF %[3]s ON %[1]s
+  WHEN OLD.fid = NEW.fid AND (NEW.%[3]s NOTNULL AND NOT ST_IsEmpty(NEW.%[3]s))
+BEGIN
+  INSERT OR REPLACE INTO %[2]s VALUES (NEW.fid,
+    ST_MinX(NEW.%[3]s), ST_MaxX(NEW.%[3]s), ST_MinY(NEW.%[3]s), ST_MaxY(NEW.%[3]s));
+END;
+CREATE TRIGGER %[6]s AFTER UPDATE OF %[3]s ON %[1]s
+  WHEN OLD.fid = NEW.fid AND (NEW.%[3]s ISNULL OR ST_IsEmpty(NEW.%[3]s))
+BEGIN
+  DELETE FROM %[2]s WHERE id = OLD.fid;
+END;
+CREATE TRIGGER %[7]s AFTER UPDATE ON %[1]s
+  WHEN OLD.fid != NEW.fid AND (NEW.%[3]s NOTNULL AND NOT ST_IsEmpty(NEW.%[3]s))
+BEGIN
+  DELETE FROM %[2]s WHERE id = OLD.fid;
+  INSERT OR REPLACE INTO %[2]s VALUES (NEW.fid,
+    ST_MinX(NEW.%[3]s), ST_MaxX(NEW.%[3]s), ST_MinY(NEW.%[3]s), ST_MaxY(NEW.%[3]s));
+END;
+CREATE TRIGGER %[8]s AFTER UPDATE ON %[1]s
+  WHEN OLD.fid != NEW.fid AND (NEW.%[3]s ISNULL OR ST_IsEmpty(NEW.%[3]s))
+BEGIN
+  DELETE FROM %[2]s WHERE id IN (OLD.fid, NEW.fid);
+END;
+CREATE TRIGGER %[9]s AFTER DELETE ON %[1]s
+  WHEN OLD.%[3]s NOT NULL
+BEGIN
+  DELETE FROM %[2]s WHERE id = OLD.fid;
+END;
+`
+
+// GeoPackage is a GeoPackage file being written.
+type GeoPackage struct {
+	db   *sql.DB
+	path string
+	srs  map[int]bool
+}
+
+// CreateGeoPackage creates a new GeoPackage at path. It is an error if the
+// file already exists.
+func CreateGeoPackage(path string) (*GeoPackage, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	db, err := sql.Open(gpkgDriver, path)
+	if err != nil {
+		return nil, err
+	}
+	// the registered functions are per connection, keep to one
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"PRAGMA application_id = 1196444487", // GPKG
+		"PRAGMA user_version = 10200",
+		gpkgSchema,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("error creating %s : %s", path, err)
+		}
+	}
+	g := &GeoPackage{db, path, map[int]bool{-1: true, 0: true}}
+	if err := g.addSRS(4326); err != nil {
+		g.Discard()
+		return nil, err
+	}
+	return g, nil
+}
+
+// Close closes the GeoPackage file.
+func (g *GeoPackage) Close() error {
+	return g.db.Close()
+}
+
+// Discard closes and removes the GeoPackage file, for when writing it
+// failed.
+func (g *GeoPackage) Discard() error {
+	g.db.Close()
+	return os.Remove(g.path)
+}
+
+// addSRS inserts the spatial reference system for an EPSG code, if not
+// already present.
+func (g *GeoPackage) addSRS(code int) error {
+	if g.srs[code] {
+		return nil
+	}
+	name, definition := fmt.Sprintf("EPSG:%d", code), "undefined"
+	if c, err := proj.Lookup(code); err == nil {
+		name = c.Name
+		if wkt, err := c.WKT(); err == nil {
+			definition = wkt
+		}
+	}
+	_, err := g.db.Exec("INSERT INTO gpkg_spatial_ref_sys VALUES (?, ?, 'EPSG', ?, ?, NULL)", name, code, code, definition)
+	if err != nil {
+		return err
+	}
+	g.srs[code] = true
+	return nil
+}
+
+// GeoPackageLayer is a feature table of a GeoPackage being written. Fields
+// whose names collide with the fid, geom or id columns, which SQLite compares
+// case insensitively, are renamed and reported as warnings.
+type GeoPackageLayer struct {
+	warnings
+	gp       *GeoPackage
+	table    string
+	srsID    int
+	fields   []Field
+	idColumn bool
+	insert   string
+	bounds   wfs.BBox
+	empty    bool
+}
+
+// CreateLayer creates the feature table, its geometry column and R-tree
+// spatial index for l. The original feature IDs are stored in an "id" column
+// unless l has a field of that name.
+func (g *GeoPackage) CreateLayer(l Layer) (*GeoPackageLayer, error) {
+	srsID := 4326
+	if l.Crs != "" {
+		code, ok := wfs.EPSGCode(l.Crs)
+		if !ok {
+			return nil, fmt.Errorf("unsupported CRS %q", l.Crs)
+		}
+		srsID = code
+	}
+	if err := g.addSRS(srsID); err != nil {
+		return nil, err
+	}
+	layer := &GeoPackageLayer{gp: g, table: l.Name, srsID: srsID, fields: l.Fields, idColumn: !l.hasField("id"), empty: true}
+	cols := []string{"fid INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL", "geom GEOMETRY"}
+	names := []string{"geom"}
+	if layer.idColumn {
+		cols = append(cols, "id TEXT")
+		names = append(names, "id")
+	}
+	used := map[string]bool{"fid": true, "geom": true, "id": layer.idColumn}
+	for _, f := range l.Fields {
+		name := f.Name
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s_%d", f.Name, i)
+		}
+		used[strings.ToLower(name)] = true
+		if name != f.Name {
+			layer.warn("field %q renamed to %q", f.Name, name)
+		}
+		cols = append(cols, quote(name)+" "+gpkgSQLTypes[f.Type])
+		names = append(names, quote(name))
+	}
+	table := quote(l.Name)
+	rtree := "rtree_" + l.Name + "_geom"
+	trigger := func(suffix string) string { return quote(rtree + "_" + suffix) }
+	description := l.Description
+	if description == "" {
+		description = l.Title
+	}
+	stmts := []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(cols, ", ")),
+		fmt.Sprintf("INSERT INTO gpkg_contents (table_name, data_type, identifier, description, srs_id) VALUES (%s, 'features', %s, %s, %d)",
+			sqlString(l.Name), sqlString(l.Name), sqlString(description), srsID),
+		fmt.Sprintf("INSERT INTO gpkg_geometry_columns VALUES (%s, 'geom', 'GEOMETRY', %d, 0, 0)", sqlString(l.Name), srsID),
+		fmt.Sprintf("CREATE VIRTUAL TABLE %s USING rtree(id, minx, maxx, miny, maxy)", quote(rtree)),
+		fmt.Sprintf(rtreeTriggers, table, quote(rtree), "geom",
+			trigger("insert"), trigger("update1"), trigger("update2"), trigger("update3"), trigger("update4"), trigger("delete")),
+		fmt.Sprintf("INSERT INTO gpkg_extensions VALUES (%s, 'geom', 'gpkg_rtree_index', 'http://www.geopackage.org/spec120/#extension_rtree', 'write-only')",
+			sqlString(l.Name)),
+	}
+	for _, stmt := range stmts {
+		if _, err := g.db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("error creating layer %s : %s", l.Name, err)
+		}
+	}
+	params := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	layer.insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), params)
+	return layer, nil
+}
+
+func sqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Write inserts the features in a single transaction.
+func (l *GeoPackageLayer) Write(features []wfs.Feature) error {
+	tx, err := l.gp.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(l.insert)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, f := range features {
+		args := []interface{}{nil}
+		if f.Geometry != nil {
+			blob, err := gpkgGeometry(f.Geometry, l.srsID)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			args[0] = blob
+			if b, ok := f.Geometry.Bounds(); ok {
+				l.extend(b)
+			}
+		}
+		if l.idColumn {
+			if id := featureID(f); id != "" {
+				args = append(args, id)
+			} else {
+				args = append(args, nil)
+			}
+		}
+		for _, fd := range l.fields {
+			args = append(args, fieldValue(fd.Type, f.Properties[fd.Name]))
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error writing feature %v : %s", f.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (l *GeoPackageLayer) extend(b wfs.BBox) {
+	if l.empty {
+		l.bounds, l.empty = b, false
+		return
+	}
+	l.bounds = wfs.BBox{
+		math.Min(l.bounds[0], b[0]), math.Min(l.bounds[1], b[1]),
+		math.Max(l.bounds[2], b[2]), math.Max(l.bounds[3], b[3]),
+	}
+}
+
+// Close records the extent of the written features in gpkg_contents.
+func (l *GeoPackageLayer) Close() error {
+	if l.empty {
+		return nil
+	}
+	b := l.bounds
+	_, err := l.gp.db.Exec(`UPDATE gpkg_contents SET min_x = ?, min_y = ?, max_x = ?, max_y = ?,
+		last_change = strftime('%Y-%m-%dT%H:%M:%fZ','now') WHERE table_name = ?`, b[0], b[1], b[2], b[3], l.table)
+	return err
+}
+
+// Discard drops the feature table and its spatial index and removes the
+// layer from the GeoPackage metadata.
+func (l *GeoPackageLayer) Discard() error {
+	name := sqlString(l.table)
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS " + quote(l.table),
+		"DROP TABLE IF EXISTS " + quote("rtree_"+l.table+"_geom"),
+		"DELETE FROM gpkg_extensions WHERE table_name = " + name,
+		"DELETE FROM gpkg_geometry_columns WHERE table_name = " + name,
+		"DELETE FROM gpkg_contents WHERE table_name = " + name,
+	} {
+		if _, err := l.gp.db.Exec(stmt); err != nil {
+			return fmt.Errorf("error discarding layer %s : %s", l.table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+func testFeatures() []wfs.Feature {
+	return []wfs.Feature{
+		{ID: "a", Geometry: &wfs.Geometry{Type: "Point", Point: wfs.Position{1, 2}},
+			Properties: map[string]interface{}{"name": "first", "pop": 10.0}},
+		{ID: 2.0, Geometry: &wfs.Geometry{Type: "Polygon", Polygon: [][]wfs.Position{{{0, 0}, {4, 0}, {4, 3}, {0, 0}}}},
+			Properties: map[string]interface{}{"name": "second", "tags": []interface{}{"x"}}},
+		{Properties: map[string]interface{}{"name": "third"}},
+	}
+}
+
+func TestGeoPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.gpkg")
+	gp, err := CreateGeoPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	features := testFeatures()
+	l, err := gp.CreateLayer(Layer{Name: "places", Fields: InferFields(features)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write(features); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open(gpkgDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM rtree_places_geom").Scan(&count); err != nil || count != 2 {
+		t.Errorf("rtree entries %d %v", count, err)
+	}
+	var maxX float64
+	if err := db.QueryRow("SELECT max_x FROM gpkg_contents WHERE table_name = 'places'").Scan(&maxX); err != nil || maxX != 4 {
+		t.Errorf("extent %f %v", maxX, err)
+	}
+	var id, tags string
+	var pop sql.NullInt64
+	if err := db.QueryRow("SELECT id, pop, tags FROM places WHERE name = 'second'").Scan(&id, &pop, &tags); err != nil {
+		t.Fatal(err)
+	}
+	if id != "2" || pop.Valid || tags != `["x"]` {
+		t.Errorf("row %s %v %s", id, pop, tags)
+	}
+	var minY float64
+	if err := db.QueryRow("SELECT ST_MinY(geom) FROM places WHERE name = 'first'").Scan(&minY); err != nil || minY != 2 {
+		t.Errorf("envelope %f %v", minY, err)
+	}
+}
+
+func TestGeoPackageColumnNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.gpkg")
+	gp, err := CreateGeoPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := gp.CreateLayer(Layer{Name: "places", Fields: []Field{{"fid", Integer}, {"Geom", String}, {"ID", String}, {"name", String}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Warnings()) != 3 {
+		t.Errorf("warnings %q", l.Warnings())
+	}
+	err = l.Write([]wfs.Feature{{ID: "a", Properties: map[string]interface{}{"fid": 7.0, "Geom": "g", "ID": "b", "name": "n"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fid int
+	var geom, id, origID string
+	if err := gp.db.QueryRow("SELECT fid_2, Geom_2, ID_2, id FROM places").Scan(&fid, &geom, &origID, &id); err != nil {
+		t.Fatal(err)
+	}
+	if fid != 7 || geom != "g" || origID != "b" || id != "a" {
+		t.Errorf("row %d %s %s %s", fid, geom, origID, id)
+	}
+	if err := gp.Discard(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s not removed", path)
+	}
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// HarvestState records the progress of a harvest so that an interrupted
+// harvest can be resumed without fetching completed pages again.
+type HarvestState struct {
+	Collections map[string]*CollectionState `json:"collections"`
+}
+
+// CollectionState is the progress of harvesting a single collection.
+type CollectionState struct {
+	// Next is the URL of the next page to fetch, empty before the first.
+	Next string `json:"next,omitempty"`
+	// Size is the length of the output file after the last completed page.
+	Size     int64 `json:"size"`
+	Features int   `json:"features"`
+	Done     bool  `json:"done"`
+}
+
+// LoadHarvestState reads the state file at path. A missing file yields an
+// empty state.
+func LoadHarvestState(path string) (*HarvestState, error) {
+	s := &HarvestState{Collections: map[string]*CollectionState{}}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if s.Collections == nil {
+		s.Collections = map[string]*CollectionState{}
+	}
+	return s, nil
+}
+
+// Collection returns the state of the named collection, adding it if new.
+func (s *HarvestState) Collection(name string) *CollectionState {
+	cs, ok := s.Collections[name]
+	if !ok {
+		cs = &CollectionState{}
+		s.Collections[name] = cs
+	}
+	return cs
+}
+
+// Save writes the state to path. The file is replaced atomically so an
+// interruption never leaves a partial state behind.
+func (s *HarvestState) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// HarvestCollection appends the features of c matching q to the
+// newline-delimited GeoJSON file at path, starting where st left off.
+// checkpoint is called after every page, once st has been updated, and
+// should persist the state. Output written after the last checkpoint of an
+// interrupted harvest is discarded on resume.
+func HarvestCollection(ctx context.Context, c wfs.Collection, q wfs.ItemsQuery, path string, st *CollectionState, checkpoint func() error) error {
+	if st.Done {
+		return nil
+	}
+	if st.Next == "" {
+		// nothing completed yet
+		st.Size, st.Features = 0, 0
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(st.Size); err != nil {
+		return err
+	}
+	if _, err := f.Seek(st.Size, io.SeekStart); err != nil {
+		return err
+	}
+	w := NewGeoJSONSeq(f, false)
+	pages := c.ResumePages(ctx, q, st.Next)
+	for pages.Next() {
+		features := pages.Page().Features
+		if err := w.Write(features); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		size, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		st.Size, st.Features = size, st.Features+len(features)
+		st.Next = pages.NextURL()
+		st.Done = st.Next == ""
+		if err := checkpoint(); err != nil {
+			return err
+		}
+	}
+	if err := pages.Err(); err != nil {
+		return err
+	}
+	return nil
+}
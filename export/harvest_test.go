@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// pagedServer serves a collection of 3 pages with 2 features each.
+func pagedServer(requests map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/":
+			w.Write([]byte(`{"openapi": "3.0.0", "info": {"title": "test", "version": "1"}, "paths": {}}`))
+			return
+		case "/conformance/":
+			w.Write([]byte(`{"conformsTo": []}`))
+			return
+		case "/collections/c/items/":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		page := 0
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		requests[r.URL.RawQuery]++
+		next := ""
+		if page < 2 {
+			next = fmt.Sprintf(`{"rel": "next", "href": "?page=%d"}`, page+1)
+		}
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "links": [%s], "features": [
+			{"type": "Feature", "id": %d, "geometry": null, "properties": {}},
+			{"type": "Feature", "id": %d, "geometry": null, "properties": {}}]}`, next, 2*page, 2*page+1)
+	}))
+}
+
+func TestHarvestCollection(t *testing.T) {
+	requests := map[string]int{}
+	srv := pagedServer(requests)
+	defer srv.Close()
+	svc, err := wfs.NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "harvest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "c.ndjson")
+	statePath := filepath.Join(dir, "state.json")
+
+	state, _ := LoadHarvestState(statePath)
+	st := state.Collection("c")
+	interrupt := errors.New("interrupted")
+	checkpoints := 0
+	err = HarvestCollection(context.Background(), svc.Collection("c"), wfs.ItemsQuery{}, path, st, func() error {
+		checkpoints++
+		if checkpoints == 2 {
+			// the second page is written but not recorded
+			return interrupt
+		}
+		return state.Save(statePath)
+	})
+	if err != interrupt {
+		t.Fatalf("expected interruption, got %v", err)
+	}
+
+	state, err = LoadHarvestState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = state.Collection("c")
+	if st.Features != 2 || st.Done || st.Next == "" {
+		t.Fatalf("state %+v", st)
+	}
+	err = HarvestCollection(context.Background(), svc.Collection("c"), wfs.ItemsQuery{}, path, st, func() error {
+		return state.Save(statePath)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Done || st.Features != 6 {
+		t.Errorf("state %+v", st)
+	}
+	f, _ := os.Open(path)
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines++
+	}
+	if lines != 6 {
+		t.Errorf("features written %d", lines)
+	}
+	if requests[""] != 1 || requests["page=1"] != 2 || requests["page=2"] != 1 {
+		t.Errorf("requests %v", requests)
+	}
+}
@@ -0,0 +1,478 @@
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ischneider/go-wfs-client/proj"
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// Shapefile shape types.
+const (
+	shpNull       = 0
+	shpPoint      = 1
+	shpPolyLine   = 3
+	shpPolygon    = 5
+	shpMultiPoint = 8
+)
+
+var shpTypes = map[string]int32{
+	"Point":           shpPoint,
+	"MultiPoint":      shpMultiPoint,
+	"LineString":      shpPolyLine,
+	"MultiLineString": shpPolyLine,
+	"Polygon":         shpPolygon,
+	"MultiPolygon":    shpPolygon,
+}
+
+var shpTypeNames = map[int32]string{
+	shpPoint:      "point",
+	shpMultiPoint: "multipoint",
+	shpPolyLine:   "polyline",
+	shpPolygon:    "polygon",
+}
+
+const (
+	dbfNameLen   = 10
+	dbfStringLen = 254
+)
+
+// dbfField is a dBase column with its fixed width.
+type dbfField struct {
+	Field
+	name     string
+	kind     byte
+	width    int
+	decimals int
+}
+
+// Shapefile writes an ESRI Shapefile: the .shp geometries, the .shx index,
+// the .dbf attributes and, if the CRS is known, the .prj definition. The
+// shape type is taken from the first geometry written; geometries of other
+// types are written as null shapes. Field names are truncated to 10 and text
+// values to 254 bytes, both reported as warnings.
+type Shapefile struct {
+	warnings
+	base          string
+	shp, shx, dbf *os.File
+	shpW, dbfW    *bufio.Writer
+	fields        []dbfField
+	idField       bool
+	shapeType     int32
+	offset        int64 // in bytes
+	records       int
+	bounds        wfs.BBox
+	empty         bool
+}
+
+// CreateShapefile creates the files of the Shapefile. path is the name of
+// the .shp file.
+func CreateShapefile(path string, l Layer) (*Shapefile, error) {
+	base := strings.TrimSuffix(path, ".shp")
+	s := &Shapefile{base: base, offset: 100, empty: true, idField: !l.hasField("id")}
+	if s.idField {
+		s.fields = append(s.fields, dbfField{Field: Field{"id", String}})
+	}
+	for _, f := range l.Fields {
+		s.fields = append(s.fields, dbfField{Field: f})
+	}
+	s.layoutFields()
+	var err error
+	for _, f := range []struct {
+		file **os.File
+		ext  string
+	}{{&s.shp, ".shp"}, {&s.shx, ".shx"}, {&s.dbf, ".dbf"}} {
+		if *f.file, err = os.Create(base + f.ext); err != nil {
+			s.closeFiles()
+			return nil, err
+		}
+	}
+	if err := ioutil.WriteFile(base+".cpg", []byte("UTF-8"), 0644); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	if wkt := shapefilePrj(l.Crs); wkt != "" {
+		if err := ioutil.WriteFile(base+".prj", []byte(wkt), 0644); err != nil {
+			s.closeFiles()
+			return nil, err
+		}
+	} else {
+		s.warn("no .prj written for unknown CRS %q", l.Crs)
+	}
+	s.shpW = bufio.NewWriter(s.shp)
+	s.dbfW = bufio.NewWriter(s.dbf)
+	// headers are completed on Close
+	s.shpW.Write(make([]byte, 100))
+	s.shx.Write(make([]byte, 100))
+	if err := s.writeDBFHeader(s.dbfW); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	return s, nil
+}
+
+func shapefilePrj(crs string) string {
+	if crs == "" {
+		crs = wfs.CRS84
+	}
+	code, ok := wfs.EPSGCode(crs)
+	if !ok {
+		return ""
+	}
+	c, err := proj.Lookup(code)
+	if err != nil {
+		return ""
+	}
+	wkt, _ := c.WKT()
+	return wkt
+}
+
+// layoutFields assigns unique dBase names, types and widths to the fields.
+func (s *Shapefile) layoutFields() {
+	used := map[string]bool{}
+	for i := range s.fields {
+		f := &s.fields[i]
+		name := truncate(f.Name, dbfNameLen)
+		for n := 1; used[strings.ToUpper(name)]; n++ {
+			suffix := strconv.Itoa(n)
+			name = truncate(f.Name, dbfNameLen-len(suffix)) + suffix
+		}
+		if name != f.Name {
+			s.warn("field %q truncated to %q", f.Name, name)
+		}
+		used[strings.ToUpper(name)] = true
+		f.name = name
+		switch f.Type {
+		case Integer:
+			f.kind, f.width = 'N', 18
+		case Real:
+			f.kind, f.width, f.decimals = 'N', 24, 15
+		case Boolean:
+			f.kind, f.width = 'L', 1
+		case Date:
+			f.kind, f.width = 'D', 8
+		default:
+			f.kind, f.width = 'C', dbfStringLen
+		}
+	}
+}
+
+func (s *Shapefile) recordLength() int {
+	n := 1
+	for _, f := range s.fields {
+		n += f.width
+	}
+	return n
+}
+
+func (s *Shapefile) writeDBFHeader(w io.Writer) error {
+	h := make([]byte, 32+32*len(s.fields)+1)
+	now := time.Now()
+	h[0] = 0x03
+	h[1], h[2], h[3] = byte(now.Year()-1900), byte(now.Month()), byte(now.Day())
+	binary.LittleEndian.PutUint32(h[4:], uint32(s.records))
+	binary.LittleEndian.PutUint16(h[8:], uint16(len(h)))
+	binary.LittleEndian.PutUint16(h[10:], uint16(s.recordLength()))
+	for i, f := range s.fields {
+		d := h[32+i*32:]
+		copy(d[:11], f.name)
+		d[11] = f.kind
+		d[16] = byte(f.width)
+		d[17] = byte(f.decimals)
+	}
+	h[len(h)-1] = 0x0D
+	_, err := w.Write(h)
+	return err
+}
+
+// Write implements FeatureWriter.
+func (s *Shapefile) Write(features []wfs.Feature) error {
+	for _, f := range features {
+		content, err := s.shape(f.Geometry)
+		if err != nil {
+			return err
+		}
+		s.records++
+		var rh [8]byte
+		binary.BigEndian.PutUint32(rh[:], uint32(s.records))
+		binary.BigEndian.PutUint32(rh[4:], uint32(len(content)/2))
+		var ix [8]byte
+		binary.BigEndian.PutUint32(ix[:], uint32(s.offset/2))
+		binary.BigEndian.PutUint32(ix[4:], uint32(len(content)/2))
+		if _, err := s.shx.Write(ix[:]); err != nil {
+			return err
+		}
+		s.shpW.Write(rh[:])
+		if _, err := s.shpW.Write(content); err != nil {
+			return err
+		}
+		s.offset += int64(len(rh) + len(content))
+		if err := s.writeRecord(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Shapefile) writeRecord(f wfs.Feature) error {
+	rec := make([]byte, 0, s.recordLength())
+	rec = append(rec, ' ')
+	for i := range s.fields {
+		fd := &s.fields[i]
+		var v interface{}
+		if s.idField && i == 0 {
+			if id := featureID(f); id != "" {
+				v = id
+			}
+		} else {
+			v = fieldValue(fd.Type, f.Properties[fd.Name])
+		}
+		rec = append(rec, s.dbfValue(fd, v)...)
+	}
+	_, err := s.dbfW.Write(rec)
+	return err
+}
+
+// dbfValue formats v to the fixed width of the field.
+func (s *Shapefile) dbfValue(f *dbfField, v interface{}) []byte {
+	out := []byte(strings.Repeat(" ", f.width))
+	if v == nil {
+		return out
+	}
+	var text string
+	switch f.kind {
+	case 'N':
+		switch t := v.(type) {
+		case int64:
+			text = strconv.FormatInt(t, 10)
+		case float64:
+			text = strconv.FormatFloat(t, 'f', f.decimals, 64)
+			if len(text) > f.width {
+				text = strconv.FormatFloat(t, 'e', f.width-8, 64)
+			}
+		default:
+			return out
+		}
+		if len(text) > f.width {
+			s.warn("values of %q too wide for the field", f.Name)
+			return out
+		}
+		copy(out[f.width-len(text):], text)
+		return out
+	case 'L':
+		if b, ok := v.(bool); ok && b {
+			out[0] = 'T'
+		} else {
+			out[0] = 'F'
+		}
+		return out
+	case 'D':
+		text = strings.Replace(formatValue(v), "-", "", -1)
+		if len(text) > 8 {
+			text = text[:8]
+		}
+	default:
+		text = formatValue(v)
+	}
+	if len(text) > f.width {
+		s.warn("values of %q truncated to %d bytes", f.Name, f.width)
+		text = truncate(text, f.width)
+	}
+	copy(out, text)
+	return out
+}
+
+// truncate returns the longest prefix of s of at most n bytes that does not
+// split a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// shape encodes the record content for g.
+func (s *Shapefile) shape(g *wfs.Geometry) ([]byte, error) {
+	w := &wkbWriter{}
+	if g == nil {
+		w.uint32(shpNull)
+		return w.buf.Bytes(), nil
+	}
+	t, ok := shpTypes[g.Type]
+	if !ok {
+		s.warn("%s geometries are not supported, written as null", g.Type)
+		w.uint32(shpNull)
+		return w.buf.Bytes(), nil
+	}
+	if s.shapeType == shpNull {
+		s.shapeType = t
+	}
+	if t != s.shapeType {
+		s.warn("%s geometries do not match the %s shape type, written as null", g.Type, shpTypeNames[s.shapeType])
+		w.uint32(shpNull)
+		return w.buf.Bytes(), nil
+	}
+	b, ok := g.Bounds()
+	if !ok {
+		w.uint32(shpNull)
+		return w.buf.Bytes(), nil
+	}
+	s.extend(b)
+	w.uint32(uint32(t))
+	if t == shpPoint {
+		w.position(g.Point)
+		return w.buf.Bytes(), nil
+	}
+	for _, v := range b {
+		w.float64(v)
+	}
+	var parts [][]wfs.Position
+	switch g.Type {
+	case "MultiPoint":
+		w.uint32(uint32(len(g.MultiPoint)))
+		for _, p := range g.MultiPoint {
+			w.position(p)
+		}
+		return w.buf.Bytes(), nil
+	case "LineString":
+		parts = [][]wfs.Position{g.LineString}
+	case "MultiLineString":
+		parts = g.MultiLineString
+	case "Polygon":
+		parts = orientRings(g.Polygon)
+	case "MultiPolygon":
+		for _, p := range g.MultiPolygon {
+			parts = append(parts, orientRings(p)...)
+		}
+	}
+	points := 0
+	for _, p := range parts {
+		points += len(p)
+	}
+	w.uint32(uint32(len(parts)))
+	w.uint32(uint32(points))
+	start := 0
+	for _, p := range parts {
+		w.uint32(uint32(start))
+		start += len(p)
+	}
+	for _, p := range parts {
+		for _, pos := range p {
+			w.position(pos)
+		}
+	}
+	return w.buf.Bytes(), nil
+}
+
+// orientRings returns the rings of a polygon with the exterior ring clockwise
+// and the interior rings counter-clockwise as the Shapefile spec requires.
+func orientRings(rings [][]wfs.Position) [][]wfs.Position {
+	out := make([][]wfs.Position, len(rings))
+	for i, r := range rings {
+		clockwise := signedArea(r) < 0
+		if (i == 0) != clockwise {
+			rev := make([]wfs.Position, len(r))
+			for j, p := range r {
+				rev[len(r)-1-j] = p
+			}
+			r = rev
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// signedArea is positive for counter-clockwise rings.
+func signedArea(r []wfs.Position) float64 {
+	a := 0.0
+	for i := 0; i+1 < len(r); i++ {
+		if len(r[i]) < 2 || len(r[i+1]) < 2 {
+			continue
+		}
+		a += r[i][0]*r[i+1][1] - r[i+1][0]*r[i][1]
+	}
+	return a / 2
+}
+
+func (s *Shapefile) extend(b wfs.BBox) {
+	if s.empty {
+		s.bounds, s.empty = b, false
+		return
+	}
+	s.bounds = wfs.BBox{
+		math.Min(s.bounds[0], b[0]), math.Min(s.bounds[1], b[1]),
+		math.Max(s.bounds[2], b[2]), math.Max(s.bounds[3], b[3]),
+	}
+}
+
+// mainHeader returns the 100 byte header of the .shp and .shx files.
+func (s *Shapefile) mainHeader(length int64) []byte {
+	w := &wkbWriter{}
+	var code [24]byte
+	binary.BigEndian.PutUint32(code[:], 9994)
+	w.buf.Write(code[:])
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(length/2))
+	w.buf.Write(l[:])
+	w.uint32(1000)
+	w.uint32(uint32(s.shapeType))
+	for _, v := range s.bounds {
+		w.float64(v)
+	}
+	w.buf.Write(make([]byte, 32))
+	return w.buf.Bytes()
+}
+
+// Close completes the file headers and closes the files.
+func (s *Shapefile) Close() error {
+	defer s.closeFiles()
+	if err := s.shpW.Flush(); err != nil {
+		return err
+	}
+	s.dbfW.WriteByte(0x1A)
+	if err := s.dbfW.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.shp.WriteAt(s.mainHeader(s.offset), 0); err != nil {
+		return err
+	}
+	if _, err := s.shx.WriteAt(s.mainHeader(100+8*int64(s.records)), 0); err != nil {
+		return err
+	}
+	if _, err := s.dbf.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.writeDBFHeader(s.dbf)
+}
+
+// Discard implements FeatureWriter.
+func (s *Shapefile) Discard() error {
+	s.closeFiles()
+	var err error
+	for _, ext := range []string{".shp", ".shx", ".dbf", ".cpg", ".prj"} {
+		if rerr := os.Remove(s.base + ext); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+func (s *Shapefile) closeFiles() {
+	for _, f := range []*os.File{s.shp, s.shx, s.dbf} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
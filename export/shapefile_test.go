@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+func TestShapefile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.shp")
+	features := testFeatures()
+	features[0].Properties["a_very_long_name"] = strings.Repeat("é", 200)
+	s, err := CreateShapefile(path, Layer{Name: "places", Fields: InferFields(features)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(features); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the polygon does not match the point shape type of the first feature
+	// and the long name and value are truncated
+	if w := s.Warnings(); len(w) != 3 {
+		t.Errorf("warnings %q", w)
+	}
+
+	shp, _ := ioutil.ReadFile(path)
+	if len(shp) != 100+28+12+12 {
+		t.Fatalf("shp length %d", len(shp))
+	}
+	if l := binary.BigEndian.Uint32(shp[24:]); int(l)*2 != len(shp) {
+		t.Errorf("file length %d", l)
+	}
+	if st := binary.LittleEndian.Uint32(shp[32:]); st != shpPoint {
+		t.Errorf("shape type %d", st)
+	}
+	shx, _ := ioutil.ReadFile(filepath.Join(dir, "out.shx"))
+	if len(shx) != 100+3*8 || binary.BigEndian.Uint32(shx[108:]) != (100+28)/2 {
+		t.Errorf("shx %d", len(shx))
+	}
+
+	dbf, _ := ioutil.ReadFile(filepath.Join(dir, "out.dbf"))
+	if n := binary.LittleEndian.Uint32(dbf[4:]); n != 3 {
+		t.Errorf("records %d", n)
+	}
+	headerLen := int(binary.LittleEndian.Uint16(dbf[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(dbf[10:]))
+	if len(dbf) != headerLen+3*recordLen+1 || dbf[len(dbf)-1] != 0x1A {
+		t.Errorf("dbf length %d", len(dbf))
+	}
+	if name := strings.TrimRight(string(dbf[64:75]), "\x00"); name != "a_very_lon" {
+		t.Errorf("field name %q", name)
+	}
+}
+
+func TestShapefileFieldNames(t *testing.T) {
+	s := &Shapefile{fields: []dbfField{
+		{Field: Field{Name: "aäääää_x"}},
+		{Field: Field{Name: "aäääää_y"}},
+	}}
+	s.layoutFields()
+	for i, want := range []string{"aääää", "aääää1"} {
+		if name := s.fields[i].name; name != want || !utf8.ValidString(name) {
+			t.Errorf("field %d name %q", i, name)
+		}
+	}
+}
+
+func TestOrientRings(t *testing.T) {
+	ccw := []wfs.Position{{0, 0}, {1, 0}, {1, 1}, {0, 0}}
+	rings := orientRings([][]wfs.Position{ccw, ccw})
+	if signedArea(rings[0]) >= 0 || signedArea(rings[1]) <= 0 {
+		t.Errorf("orientation %v", rings)
+	}
+}
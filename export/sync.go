@@ -0,0 +1,214 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// Snapshot records the IDs and content hashes of the features of a
+// collection at the time of a harvest or sync.
+type Snapshot struct {
+	Collection string            `json:"collection"`
+	Taken      time.Time         `json:"taken"`
+	Features   map[string]string `json:"features"`
+}
+
+// LoadSnapshot reads the snapshot file at path. A missing file yields an
+// empty snapshot, so every feature is reported as added.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	s := &Snapshot{Features: map[string]string{}}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s : %s", path, err)
+	}
+	if s.Features == nil {
+		s.Features = map[string]string{}
+	}
+	return s, nil
+}
+
+// ReadSnapshot builds a snapshot from newline-delimited GeoJSON features,
+// such as the output of HarvestCollection.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	s := &Snapshot{Features: map[string]string{}}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) > 0 && line[0] == recordSeparator {
+			line = line[1:]
+		}
+		if len(line) == 0 {
+			continue
+		}
+		var f wfs.Feature
+		if err := json.Unmarshal(line, &f); err != nil {
+			return nil, err
+		}
+		if id := featureID(f); id != "" {
+			s.Features[id] = FeatureHash(f)
+		}
+	}
+	return s, sc.Err()
+}
+
+// Save writes the snapshot to path.
+func (s *Snapshot) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// FeatureHash returns a hash of the geometry and properties of f. Property
+// order does not affect the hash; links and the ID are not included.
+func FeatureHash(f wfs.Feature) string {
+	// map keys are marshalled in sorted order
+	b, _ := json.Marshal(struct {
+		Geometry   *wfs.Geometry          `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}{f.Geometry, f.Properties})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Diff lists the changes of a collection between two snapshots.
+type Diff struct {
+	Added    []wfs.Feature
+	Modified []wfs.Feature
+	Removed  []string
+	// Unchanged counts the features scanned without changes.
+	Unchanged int
+	// Skipped counts the features without ID, which cannot be tracked.
+	Skipped int
+	// Partial is set when the scan was narrowed by a datetime filter, so
+	// removals are not detected.
+	Partial bool
+}
+
+// Sync pages through the features of c matching q and compares them with
+// prev. It returns the changes and the snapshot to compare against next
+// time. If q has a Datetime filter only the matching features are scanned:
+// they are reported as added or modified and the other features of prev are
+// carried over, as removals cannot be detected.
+func Sync(ctx context.Context, c wfs.Collection, q wfs.ItemsQuery, prev *Snapshot) (*Diff, *Snapshot, error) {
+	next := &Snapshot{Collection: c.Name, Taken: time.Now().UTC(), Features: map[string]string{}}
+	d := &Diff{Partial: q.Datetime != ""}
+	pages := c.Pages(ctx, q)
+	for pages.Next() {
+		for _, f := range pages.Page().Features {
+			id := featureID(f)
+			if id == "" {
+				d.Skipped++
+				continue
+			}
+			h := FeatureHash(f)
+			next.Features[id] = h
+			old, ok := prev.Features[id]
+			switch {
+			case !ok:
+				d.Added = append(d.Added, f)
+			case old != h:
+				d.Modified = append(d.Modified, f)
+			default:
+				d.Unchanged++
+			}
+		}
+	}
+	if err := pages.Err(); err != nil {
+		return nil, nil, err
+	}
+	for id, h := range prev.Features {
+		if _, ok := next.Features[id]; ok {
+			continue
+		}
+		if d.Partial {
+			next.Features[id] = h
+		} else {
+			d.Removed = append(d.Removed, id)
+		}
+	}
+	sort.Strings(d.Removed)
+	return d, next, nil
+}
+
+// changedFeature is a Feature with the "change" foreign member of the
+// GeoJSON diff.
+type changedFeature struct {
+	wfs.Feature
+	Change string `json:"change"`
+}
+
+// WriteGeoJSON writes the changes as a GeoJSON FeatureCollection. Each
+// feature has a "change" member of "added", "modified" or "removed"; removed
+// features carry only their ID.
+func (d *Diff) WriteGeoJSON(w io.Writer) error {
+	features := []changedFeature{}
+	for _, f := range d.Added {
+		features = append(features, changedFeature{f, "added"})
+	}
+	for _, f := range d.Modified {
+		features = append(features, changedFeature{f, "modified"})
+	}
+	for _, id := range d.Removed {
+		features = append(features, changedFeature{wfs.Feature{Type: "Feature", ID: id}, "removed"})
+	}
+	for i := range features {
+		features[i].Type = "Feature"
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Type     string           `json:"type"`
+		Features []changedFeature `json:"features"`
+	}{"FeatureCollection", features})
+}
+
+// WriteSummary writes the counts of changes followed by a table of the
+// changed feature IDs.
+func (d *Diff) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "added\t%d\n", len(d.Added))
+	fmt.Fprintf(tw, "modified\t%d\n", len(d.Modified))
+	if d.Partial {
+		fmt.Fprintf(tw, "removed\tnot detected with a datetime filter\n")
+	} else {
+		fmt.Fprintf(tw, "removed\t%d\n", len(d.Removed))
+	}
+	fmt.Fprintf(tw, "unchanged\t%d\n", d.Unchanged)
+	if d.Skipped > 0 {
+		fmt.Fprintf(tw, "without id\t%d\n", d.Skipped)
+	}
+	if len(d.Added)+len(d.Modified)+len(d.Removed) > 0 {
+		fmt.Fprintf(tw, "\nCHANGE\tID\n")
+	}
+	for _, f := range d.Added {
+		fmt.Fprintf(tw, "added\t%s\n", featureID(f))
+	}
+	for _, f := range d.Modified {
+		fmt.Fprintf(tw, "modified\t%s\n", featureID(f))
+	}
+	for _, id := range d.Removed {
+		fmt.Fprintf(tw, "removed\t%s\n", id)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+func TestSync(t *testing.T) {
+	requests := map[string]int{}
+	srv := pagedServer(requests)
+	defer srv.Close()
+	svc, err := wfs.NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchanged := FeatureHash(wfs.Feature{Properties: map[string]interface{}{}})
+	prev := &Snapshot{Features: map[string]string{"0": unchanged, "1": "stale", "9": "gone"}}
+
+	d, next, err := Sync(context.Background(), svc.Collection("c"), wfs.ItemsQuery{}, prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Added) != 4 || len(d.Modified) != 1 || d.Unchanged != 1 || len(d.Removed) != 1 || d.Removed[0] != "9" {
+		t.Errorf("diff %+v", d)
+	}
+	if len(next.Features) != 6 || next.Features["1"] != unchanged {
+		t.Errorf("snapshot %v", next.Features)
+	}
+	var buf bytes.Buffer
+	if err := d.WriteGeoJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"change": "removed"`) {
+		t.Errorf("geojson diff %s", buf.String())
+	}
+
+	d, next, err = Sync(context.Background(), svc.Collection("c"), wfs.ItemsQuery{Datetime: "2018-01-01T00:00:00Z/.."}, prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Partial || len(d.Removed) != 0 || next.Features["9"] != "gone" {
+		t.Errorf("partial diff %+v %v", d, next.Features)
+	}
+	if requests["datetime=2018-01-01T00%3A00%3A00Z%2F.."] != 1 {
+		t.Errorf("requests %v", requests)
+	}
+	buf.Reset()
+	d.WriteSummary(&buf)
+	if !strings.Contains(buf.String(), "not detected") {
+		t.Errorf("summary %s", buf.String())
+	}
+}
+
+func TestReadSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewGeoJSONSeq(&buf, false)
+	w.Write(testFeatures())
+	w.Close()
+	s, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Features) != 2 || s.Features["a"] != FeatureHash(testFeatures()[0]) {
+		t.Errorf("snapshot %v", s.Features)
+	}
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// WKB geometry type codes.
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+var wkbTypes = map[string]uint32{
+	"Point":              wkbPoint,
+	"LineString":         wkbLineString,
+	"Polygon":            wkbPolygon,
+	"MultiPoint":         wkbMultiPoint,
+	"MultiLineString":    wkbMultiLineString,
+	"MultiPolygon":       wkbMultiPolygon,
+	"GeometryCollection": wkbGeometryCollection,
+}
+
+// wkbWriter encodes geometries as little endian, two dimensional WKB.
+type wkbWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *wkbWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	w.buf.Write(b[:])
+}
+
+func (w *wkbWriter) float64(v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	w.buf.Write(b[:])
+}
+
+func (w *wkbWriter) header(t uint32) {
+	w.buf.WriteByte(1)
+	w.uint32(t)
+}
+
+func (w *wkbWriter) position(p wfs.Position) {
+	if len(p) < 2 {
+		w.float64(math.NaN())
+		w.float64(math.NaN())
+		return
+	}
+	w.float64(p[0])
+	w.float64(p[1])
+}
+
+func (w *wkbWriter) positions(ps []wfs.Position) {
+	w.uint32(uint32(len(ps)))
+	for _, p := range ps {
+		w.position(p)
+	}
+}
+
+func (w *wkbWriter) rings(rs [][]wfs.Position) {
+	w.uint32(uint32(len(rs)))
+	for _, r := range rs {
+		w.positions(r)
+	}
+}
+
+func (w *wkbWriter) geometry(g *wfs.Geometry) error {
+	t, ok := wkbTypes[g.Type]
+	if !ok {
+		return fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	w.header(t)
+	switch t {
+	case wkbPoint:
+		w.position(g.Point)
+	case wkbLineString:
+		w.positions(g.LineString)
+	case wkbPolygon:
+		w.rings(g.Polygon)
+	case wkbMultiPoint:
+		w.uint32(uint32(len(g.MultiPoint)))
+		for _, p := range g.MultiPoint {
+			w.header(wkbPoint)
+			w.position(p)
+		}
+	case wkbMultiLineString:
+		w.uint32(uint32(len(g.MultiLineString)))
+		for _, l := range g.MultiLineString {
+			w.header(wkbLineString)
+			w.positions(l)
+		}
+	case wkbMultiPolygon:
+		w.uint32(uint32(len(g.MultiPolygon)))
+		for _, p := range g.MultiPolygon {
+			w.header(wkbPolygon)
+			w.rings(p)
+		}
+	case wkbGeometryCollection:
+		w.uint32(uint32(len(g.Geometries)))
+		for _, c := range g.Geometries {
+			if err := w.geometry(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WKB returns the two dimensional, little endian WKB encoding of g.
+func WKB(g *wfs.Geometry) ([]byte, error) {
+	w := &wkbWriter{}
+	if err := w.geometry(g); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
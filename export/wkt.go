@@ -0,0 +1,105 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// WKT returns the two dimensional well-known text of g.
+func WKT(g *wfs.Geometry) (string, error) {
+	b := &strings.Builder{}
+	if err := writeWKT(b, g); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func writeWKT(b *strings.Builder, g *wfs.Geometry) error {
+	name := strings.ToUpper(g.Type)
+	switch g.Type {
+	case "Point":
+		if len(g.Point) < 2 {
+			b.WriteString("POINT EMPTY")
+			return nil
+		}
+		b.WriteString("POINT (")
+		wktPosition(b, g.Point)
+		b.WriteString(")")
+	case "MultiPoint":
+		wktList(b, name, len(g.MultiPoint), func(i int) {
+			b.WriteString("(")
+			wktPosition(b, g.MultiPoint[i])
+			b.WriteString(")")
+		})
+	case "LineString":
+		wktList(b, name, len(g.LineString), func(i int) { wktPosition(b, g.LineString[i]) })
+	case "MultiLineString":
+		wktList(b, name, len(g.MultiLineString), func(i int) { wktPositions(b, g.MultiLineString[i]) })
+	case "Polygon":
+		wktList(b, name, len(g.Polygon), func(i int) { wktPositions(b, g.Polygon[i]) })
+	case "MultiPolygon":
+		wktList(b, name, len(g.MultiPolygon), func(i int) {
+			p := g.MultiPolygon[i]
+			b.WriteString("(")
+			for j, r := range p {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				wktPositions(b, r)
+			}
+			b.WriteString(")")
+		})
+	case "GeometryCollection":
+		var err error
+		wktList(b, name, len(g.Geometries), func(i int) {
+			if err == nil {
+				err = writeWKT(b, g.Geometries[i])
+			}
+		})
+		return err
+	default:
+		return fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	return nil
+}
+
+// wktList writes name followed by the n parenthesized, comma separated items
+// written by item, or EMPTY.
+func wktList(b *strings.Builder, name string, n int, item func(int)) {
+	b.WriteString(name)
+	if n == 0 {
+		b.WriteString(" EMPTY")
+		return
+	}
+	b.WriteString(" (")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		item(i)
+	}
+	b.WriteString(")")
+}
+
+func wktPosition(b *strings.Builder, p wfs.Position) {
+	if len(p) < 2 {
+		return
+	}
+	b.WriteString(strconv.FormatFloat(p[0], 'f', -1, 64))
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatFloat(p[1], 'f', -1, 64))
+}
+
+func wktPositions(b *strings.Builder, ps []wfs.Position) {
+	b.WriteString("(")
+	for i, p := range ps {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		wktPosition(b, p)
+	}
+	b.WriteString(")")
+}
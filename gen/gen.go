@@ -0,0 +1,427 @@
+// Package gen generates Go packages with typed clients for WFS3 services.
+//
+// The generated package wraps a wfs.Service: every operation of the spec
+// becomes a method of its Client taking a struct of the operation
+// parameters, and every collection gets a type whose Items and Item methods
+// return features with the properties of its schema decoded into a struct.
+package gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// Options configure Generate.
+type Options struct {
+	// Package is the name of the generated package.
+	Package string
+	// Offline skips requesting the collections and their schemas, so that
+	// only the operations are generated.
+	Offline bool
+}
+
+type file struct {
+	Package     string
+	Source      string
+	Strings     bool
+	Operations  []operation
+	Collections []collection
+}
+
+type operation struct {
+	ID, Method, Path string
+	Name, Params     string
+	Doc              []string
+	Fields           []field
+	// Body and ContentType name the fields of the request body, if any
+	Body, ContentType string
+}
+
+type field struct {
+	Name, Key, Type string
+	Doc             []string
+	// Optional fields are pointers, or slices, set only if given
+	Optional, Array bool
+}
+
+type collection struct {
+	Key, Title   string
+	Accessor     string
+	Type, Props  string
+	Feature      string
+	Query, Maker string
+	Fields       []field
+	// Untyped collections have no published schema and decode their
+	// properties into a map
+	Untyped bool
+}
+
+// Generate returns the Go source of a package with a typed client for svc.
+// Collections whose schema cannot be retrieved get their properties as a
+// map.
+func Generate(ctx context.Context, svc wfs.Service, opts Options) ([]byte, error) {
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "client"
+	}
+	if !isIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	f := file{Package: pkg, Source: svc.Info().SpecURL}
+	if f.Source == "" {
+		f.Source = svc.Info().URL
+	}
+	types := names{"Client": true, "New": true}
+	methods := names{}
+	for _, op := range svc.Operations() {
+		o := operation{
+			ID:     op.ID,
+			Method: op.Method,
+			Path:   op.Path,
+			Name:   methods.unique(goName(op.ID)),
+			Doc:    lines(op.Description),
+		}
+		fields := names{}
+		if op.Body != nil {
+			o.Body = fields.unique("Body")
+			o.ContentType = fields.unique("ContentType")
+		}
+		for _, p := range op.Params {
+			fl := field{
+				Name:     fields.unique(goName(p.Name)),
+				Key:      p.Name,
+				Doc:      lines(p.Description),
+				Optional: !p.Required,
+			}
+			fl.Type, fl.Array = paramType(p.Type)
+			f.Strings = f.Strings || fl.Array
+			o.Fields = append(o.Fields, fl)
+		}
+		if len(o.Fields) > 0 || o.Body != "" {
+			o.Params = types.unique(o.Name + "Params")
+		}
+		f.Operations = append(f.Operations, o)
+	}
+	if opts.Offline {
+		return render(f)
+	}
+	infos, err := svc.Collections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	for _, info := range infos {
+		c := collection{
+			Key:      info.Name,
+			Title:    info.Title,
+			Accessor: methods.unique(goName(info.Name)),
+		}
+		base := types.uniqueGroup(goName(info.Name), "Collection", "Properties", "Feature", "Query")
+		c.Type, c.Props, c.Feature, c.Query = base+"Collection", base+"Properties", base+"Feature", base+"Query"
+		c.Maker = "new" + base + "Feature"
+		props, err := svc.Collection(info.Name).Schema(ctx)
+		if err != nil || len(props) == 0 {
+			c.Untyped = true
+		}
+		fields := names{}
+		for _, p := range props {
+			// such names cannot be given in a struct tag
+			if strings.ContainsAny(p.Name, "\",`") || strings.IndexFunc(p.Name, unicode.IsControl) >= 0 {
+				continue
+			}
+			c.Fields = append(c.Fields, field{
+				Name: fields.unique(goName(p.Name)),
+				Key:  p.Name,
+				Type: propertyType(p.Type),
+			})
+		}
+		f.Collections = append(f.Collections, c)
+	}
+	return render(f)
+}
+
+func render(f file) ([]byte, error) {
+	var b bytes.Buffer
+	if err := source.Execute(&b, f); err != nil {
+		return nil, err
+	}
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code : %s", err)
+	}
+	return out, nil
+}
+
+// paramType returns the Go type of a parameter with the JSON schema type t.
+// Arrays are sent comma separated.
+func paramType(t string) (string, bool) {
+	switch t {
+	case "integer":
+		return "int64", false
+	case "number":
+		return "float64", false
+	case "boolean":
+		return "bool", false
+	case "array":
+		return "[]string", true
+	}
+	return "string", false
+}
+
+// propertyType returns the Go type of a feature property. Scalars are
+// pointers as properties may be null or absent.
+func propertyType(t string) string {
+	switch t {
+	case "integer":
+		return "*int64"
+	case "number":
+		return "*float64"
+	case "boolean":
+		return "*bool"
+	case "string":
+		return "*string"
+	case "array":
+		return "[]interface{}"
+	case "object":
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+// goName returns an exported Go identifier for s, as in "CollectionId" for
+// "collectionId" or "Roads2019" for "roads-2019".
+func goName(s string) string {
+	var id []rune
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		id = append(id, r)
+	}
+	if len(id) == 0 || !unicode.IsUpper(id[0]) {
+		id = append([]rune("X"), id...)
+	}
+	return string(id)
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// line returns s on a single line, for use in a comment.
+func line(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func lines(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// names keeps identifiers unique within a scope.
+type names map[string]bool
+
+// unique returns name, or name with the lowest number appended that makes
+// it unused, and marks it used.
+func (n names) unique(name string) string {
+	return n.uniqueGroup(name, "")
+}
+
+// uniqueGroup returns a base for which base+suffix is unused for all of
+// the suffixes, and marks those used.
+func (n names) uniqueGroup(name string, suffixes ...string) string {
+	base := name
+	for i := 2; ; i++ {
+		free := true
+		for _, s := range suffixes {
+			free = free && !n[base+s]
+		}
+		if free {
+			break
+		}
+		base = fmt.Sprintf("%s%d", name, i)
+	}
+	for _, s := range suffixes {
+		n[base+s] = true
+	}
+	return base
+}
+
+var source = template.Must(template.New("source").Funcs(template.FuncMap{"line": line}).Parse(`// Code generated by wfs gen from {{line .Source}}; DO NOT EDIT.
+
+// Package {{.Package}} is a typed client for the service described by
+// {{line .Source}}.
+package {{.Package}}
+
+import (
+	"context"
+	"io/ioutil"
+{{- if .Strings}}
+	"strings"
+{{- end}}
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// Client calls the operations of the service.
+type Client struct {
+	svc wfs.Service
+}
+
+// New returns a Client for svc, connected to a service with the spec the
+// package was generated from.
+func New(svc wfs.Service) Client {
+	return Client{svc}
+}
+
+// call invokes the operation, decoding the response into v or discarding
+// it if v is nil.
+func (c Client) call(ctx context.Context, id string, params map[string]interface{}, contentType string, body []byte, v interface{}) error {
+	op, err := c.svc.GetOperation(id)
+	if err != nil {
+		return err
+	}
+	call, err := op.Call(params)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		if call, err = call.WithBody(contentType, body); err != nil {
+			return err
+		}
+	}
+	call = call.WithContext(ctx)
+	if v == nil {
+		return call.ExecuteWriter(ioutil.Discard)
+	}
+	return call.Decode(v)
+}
+{{range .Operations}}
+{{- if .Params}}
+// {{.Params}} are the parameters of {{line .ID}}.
+type {{.Params}} struct {
+{{- range .Fields}}
+{{- range .Doc}}
+	// {{.}}
+{{- end}}
+	{{.Name}} {{if and .Optional (not .Array)}}*{{end}}{{.Type}}
+{{- end}}
+{{- if .Body}}
+	// {{.Body}} is the request body, sent as {{.ContentType}}, which may be
+	// empty if the operation accepts a single media type.
+	{{.Body}} []byte
+	{{.ContentType}} string
+{{- end}}
+}
+{{end}}
+// {{.Name}} calls {{line .ID}}, {{.Method}} {{line .Path}}.
+{{- if .Doc}}
+//
+{{- range .Doc}}
+// {{.}}
+{{- end}}
+{{- end}}
+func (c Client) {{.Name}}(ctx context.Context, {{if .Params}}p {{.Params}}, {{end}}v interface{}) error {
+	params := map[string]interface{}{}
+{{- range .Fields}}
+{{- if .Array}}
+	if p.{{.Name}} != nil {
+		params[{{printf "%q" .Key}}] = strings.Join(p.{{.Name}}, ",")
+	}
+{{- else if .Optional}}
+	if p.{{.Name}} != nil {
+		params[{{printf "%q" .Key}}] = *p.{{.Name}}
+	}
+{{- else}}
+	params[{{printf "%q" .Key}}] = p.{{.Name}}
+{{- end}}
+{{- end}}
+	return c.call(ctx, {{printf "%q" .ID}}, params, {{if .Body}}p.{{.ContentType}}, p.{{.Body}}{{else}}"", nil{{end}}, v)
+}
+{{end}}
+{{- range .Collections}}
+// {{.Type}} is the {{line .Key}} collection{{if .Title}}, {{line .Title}}{{end}}.
+type {{.Type}} struct {
+	c wfs.Collection
+}
+
+// {{.Accessor}} returns the {{line .Key}} collection.
+func (c Client) {{.Accessor}}() {{.Type}} {
+	return {{.Type}}{c.svc.Collection({{printf "%q" .Key}})}
+}
+
+{{if .Untyped -}}
+// {{.Props}} are the feature properties of the {{line .Key}} collection, which
+// publishes no schema.
+type {{.Props}} map[string]interface{}
+{{- else -}}
+// {{.Props}} are the feature properties of the {{line .Key}} collection.
+type {{.Props}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `json:"{{.Key}},omitempty"` + "`" + `
+{{- end}}
+}
+{{- end}}
+
+// {{.Feature}} is a feature of the {{line .Key}} collection.
+type {{.Feature}} struct {
+	ID         interface{}
+	Geometry   *wfs.Geometry
+	Properties {{.Props}}
+}
+
+func {{.Maker}}(f wfs.Feature) ({{.Feature}}, error) {
+	tf := {{.Feature}}{ID: f.ID, Geometry: f.Geometry}
+	err := f.DecodeProperties(&tf.Properties)
+	return tf, err
+}
+
+// {{.Query}} selects features of the {{line .Key}} collection.
+type {{.Query}} wfs.ItemsQuery
+
+// Items requests a page of features of the {{line .Key}} collection.
+func (c {{.Type}}) Items(ctx context.Context, q {{.Query}}) ([]{{.Feature}}, error) {
+	page, err := c.c.Items(ctx, wfs.ItemsQuery(q))
+	if err != nil {
+		return nil, err
+	}
+	features := make([]{{.Feature}}, len(page.Features))
+	for i, f := range page.Features {
+		if features[i], err = {{.Maker}}(f); err != nil {
+			return nil, err
+		}
+	}
+	return features, nil
+}
+
+// Item requests the feature of the {{line .Key}} collection with the given ID.
+func (c {{.Type}}) Item(ctx context.Context, id string) ({{.Feature}}, error) {
+	f, err := c.c.Item(ctx, id)
+	if err != nil {
+		return {{.Feature}}{}, err
+	}
+	return {{.Maker}}(f)
+}
+{{end}}`))
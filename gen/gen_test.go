@@ -0,0 +1,149 @@
+package gen
+
+import (
+	"context"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+const spec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "servers": [{"url": "http://example.com"}],
+  "paths": {
+    "/collections/{collectionId}/items": {
+      "get": {
+        "operationId": "getFeatures",
+        "description": "Fetch features.\nPaged.",
+        "parameters": [
+          {"name": "collectionId", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "description": "page size", "schema": {"type": "integer"}},
+          {"name": "properties", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}}
+        ],
+        "responses": {"200": {"description": "features"}}
+      },
+      "post": {
+        "operationId": "create-feature",
+        "parameters": [
+          {"name": "collectionId", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {"content": {"application/geo+json": {}}},
+        "responses": {"201": {"description": "created"}}
+      }
+    },
+    "/": {
+      "get": {"operationId": "getLandingPage", "responses": {"200": {"description": "links"}}}
+    }
+  }
+}`
+
+func TestGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/":
+			w.Write([]byte(spec))
+		case "/collections/":
+			w.Write([]byte(`{"collections": [{"name": "roads", "title": "Roads"}, {"name": "misc", "title": "Misc\nstuff"}]}`))
+		case "/collections/roads/schema/":
+			w.Write([]byte(`{"properties": {
+				"name": {"type": "string"},
+				"lanes": {"type": ["integer", "null"]},
+				"geom": {"format": "geometry-linestring"},
+				"bad\"name": {"type": "string"},
+				"line\nbreak": {"type": "string"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := wfs.NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Generate(context.Background(), svc, Options{Package: "roads"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"package roads",
+		`"strings"`,
+		"func (c Client) GetFeatures(ctx context.Context, p GetFeaturesParams, v interface{}) error {",
+		"// GetFeatures calls getFeatures, GET /collections/{collectionId}/items.\n//\n// Fetch features.\n// Paged.\n",
+		"\t// page size\n\tLimit      *int64\n",
+		"\tCollectionId string\n",
+		"\tProperties []string\n",
+		`params["properties"] = strings.Join(p.Properties, ",")`,
+		"func (c Client) CreateFeature(ctx context.Context, p CreateFeatureParams, v interface{}) error {",
+		`return c.call(ctx, "create-feature", params, p.ContentType, p.Body, v)`,
+		"func (c Client) GetLandingPage(ctx context.Context, v interface{}) error {",
+		"// RoadsCollection is the roads collection, Roads.",
+		"func (c Client) Roads() RoadsCollection {",
+		"\tLanes *int64  `json:\"lanes,omitempty\"`\n\tName  *string `json:\"name,omitempty\"`\n}",
+		"func (c RoadsCollection) Items(ctx context.Context, q RoadsQuery) ([]RoadsFeature, error) {",
+		"type MiscProperties map[string]interface{}",
+		"// MiscCollection is the misc collection, Misc stuff.",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if strings.Contains(code, `json:"geom`) || strings.Contains(code, "Bad") || strings.Contains(code, "Line") {
+		t.Error("geometry or untaggable property generated")
+	}
+	typeCheck(t, src)
+	if t.Failed() {
+		t.Log(code)
+	}
+
+	src, err = Generate(context.Background(), svc, Options{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "package client") || strings.Contains(string(src), "RoadsCollection") {
+		t.Errorf("offline generation\n%s", src)
+	}
+	if _, err := Generate(context.Background(), svc, Options{Package: "my-client", Offline: true}); err == nil {
+		t.Error("expected error for invalid package name")
+	}
+}
+
+func TestNames(t *testing.T) {
+	if n := goName("roads-2019"); n != "Roads2019" {
+		t.Error(n)
+	}
+	if n := goName("2019"); n != "X2019" {
+		t.Error(n)
+	}
+	n := names{"RoadsQuery": true}
+	if base := n.uniqueGroup("Roads", "Collection", "Query"); base != "Roads2" || !n["Roads2Collection"] {
+		t.Errorf("base %s %v", base, n)
+	}
+	if name := n.unique("Roads2Query"); name != "Roads2Query2" {
+		t.Error(name)
+	}
+}
+
+// typeCheck parses and type-checks the generated package.
+func typeCheck(t *testing.T, src []byte) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "client.go", src, parser.ParseComments)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conf := types.Config{Importer: importer.For("source", nil)}
+	if _, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		t.Error(err)
+	}
+}
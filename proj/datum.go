@@ -0,0 +1,110 @@
+package proj
+
+import "math"
+
+// Ellipsoid is a reference ellipsoid defined by its semi-major axis in meters
+// and its flattening.
+type Ellipsoid struct {
+	Name string
+	A    float64
+	F    float64
+}
+
+// Reference ellipsoids used by the registered CRS.
+var (
+	WGS84             = Ellipsoid{"WGS 84", 6378137, 1 / 298.257223563}
+	GRS80             = Ellipsoid{"GRS 1980", 6378137, 1 / 298.257222101}
+	Airy1830          = Ellipsoid{"Airy 1830", 6377563.396, 1 / 299.3249646}
+	Bessel1841        = Ellipsoid{"Bessel 1841", 6377397.155, 1 / 299.1528128}
+	Clarke1866        = Ellipsoid{"Clarke 1866", 6378206.4, 1 / 294.9786982}
+	International1924 = Ellipsoid{"International 1924", 6378388, 1 / 297}
+)
+
+// e2 returns the square of the first eccentricity.
+func (e Ellipsoid) e2() float64 {
+	return e.F * (2 - e.F)
+}
+
+// Datum is a geodetic datum expressed by its ellipsoid and the 7 parameter
+// (position vector) Helmert transformation to WGS 84. Translations are in
+// meters, rotations in arc-seconds and scale in parts per million.
+type Datum struct {
+	Name      string
+	Ellipsoid Ellipsoid
+	ToWGS84   [7]float64
+}
+
+// Datums used by the registered CRS. ETRS89, NAD83 and the like are treated
+// as coincident with WGS 84, which is correct to about a meter.
+var (
+	DatumWGS84  = Datum{"WGS_1984", WGS84, [7]float64{}}
+	DatumETRS89 = Datum{"European_Terrestrial_Reference_System_1989", GRS80, [7]float64{}}
+	DatumNAD83  = Datum{"North_American_Datum_1983", GRS80, [7]float64{}}
+	DatumGDA94  = Datum{"Geocentric_Datum_of_Australia_1994", GRS80, [7]float64{}}
+	DatumGDA20  = Datum{"Geocentric_Datum_of_Australia_2020", GRS80, [7]float64{}}
+	DatumRGF93  = Datum{"Reseau_Geodesique_Francais_1993", GRS80, [7]float64{}}
+	DatumNZGD2K = Datum{"New_Zealand_Geodetic_Datum_2000", GRS80, [7]float64{}}
+	DatumSWEREF = Datum{"SWEREF99", GRS80, [7]float64{}}
+	DatumOSGB36 = Datum{"OSGB_1936", Airy1830, [7]float64{446.448, -125.157, 542.06, 0.15, 0.247, 0.842, -20.489}}
+	DatumDHDN   = Datum{"Deutsches_Hauptdreiecksnetz", Bessel1841, [7]float64{598.1, 73.7, 418.2, 0.202, 0.045, -2.455, 6.7}}
+	DatumED50   = Datum{"European_Datum_1950", International1924, [7]float64{-87, -98, -121, 0, 0, 0, 0}}
+	DatumNAD27  = Datum{"North_American_Datum_1927", Clarke1866, [7]float64{-8, 160, 176, 0, 0, 0, 0}}
+)
+
+// toGeocentric converts geodetic coordinates in radians to earth centered
+// cartesian coordinates.
+func (e Ellipsoid) toGeocentric(lam, phi, h float64) (x, y, z float64) {
+	e2 := e.e2()
+	sp, cp := math.Sincos(phi)
+	n := e.A / math.Sqrt(1-e2*sp*sp)
+	x = (n + h) * cp * math.Cos(lam)
+	y = (n + h) * cp * math.Sin(lam)
+	z = (n*(1-e2) + h) * sp
+	return
+}
+
+// fromGeocentric is the inverse of toGeocentric.
+func (e Ellipsoid) fromGeocentric(x, y, z float64) (lam, phi, h float64) {
+	e2 := e.e2()
+	p := math.Hypot(x, y)
+	lam = math.Atan2(y, x)
+	phi = math.Atan2(z, p*(1-e2))
+	for i := 0; i < 10; i++ {
+		sp := math.Sin(phi)
+		n := e.A / math.Sqrt(1-e2*sp*sp)
+		h = p/math.Cos(phi) - n
+		next := math.Atan2(z, p*(1-e2*n/(n+h)))
+		if math.Abs(next-phi) < 1e-12 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+	return
+}
+
+// helmert applies the position vector transformation p, or its inverse.
+func helmert(p [7]float64, inverse bool, x, y, z float64) (float64, float64, float64) {
+	const arcsec = math.Pi / (180 * 3600)
+	tx, ty, tz := p[0], p[1], p[2]
+	rx, ry, rz := p[3]*arcsec, p[4]*arcsec, p[5]*arcsec
+	s := 1 + p[6]*1e-6
+	if inverse {
+		x, y, z = x-tx, y-ty, z-tz
+		x, y, z = x/s, y/s, z/s
+		return x + rz*y - ry*z, -rz*x + y + rx*z, ry*x - rx*y + z
+	}
+	return tx + s*(x-rz*y+ry*z), ty + s*(rz*x+y-rx*z), tz + s*(-ry*x+rx*y+z)
+}
+
+// shift converts geodetic coordinates in radians from datum d to datum to.
+func (d Datum) shift(to Datum, lam, phi float64) (float64, float64) {
+	if d.ToWGS84 == to.ToWGS84 && math.Abs(d.Ellipsoid.A-to.Ellipsoid.A) < 1e-3 {
+		return lam, phi
+	}
+	x, y, z := d.Ellipsoid.toGeocentric(lam, phi, 0)
+	x, y, z = helmert(d.ToWGS84, false, x, y, z)
+	x, y, z = helmert(to.ToWGS84, true, x, y, z)
+	lam, phi, _ = to.Ellipsoid.fromGeocentric(x, y, z)
+	return lam, phi
+}
@@ -0,0 +1,72 @@
+package proj
+
+import "fmt"
+
+func init() {
+	for _, c := range []CRS{
+		{4326, "WGS 84", DatumWGS84, nil},
+		{4258, "ETRS89", DatumETRS89, nil},
+		{4269, "NAD83", DatumNAD83, nil},
+		{4283, "GDA94", DatumGDA94, nil},
+		{7844, "GDA2020", DatumGDA20, nil},
+		{4267, "NAD27", DatumNAD27, nil},
+		{4230, "ED50", DatumED50, nil},
+		{4277, "OSGB36", DatumOSGB36, nil},
+		{4314, "DHDN", DatumDHDN, nil},
+		{3857, "WGS 84 / Pseudo-Mercator", DatumWGS84, webMercator{}},
+		{900913, "Google Maps Global Mercator", DatumWGS84, webMercator{}},
+		{27700, "OSGB36 / British National Grid", DatumOSGB36,
+			NewTransverseMercator(Airy1830, 49, -2, 0.9996012717, 400000, -100000)},
+		{2154, "RGF93 / Lambert-93", DatumRGF93,
+			NewLambertConformalConic2SP(GRS80, 46.5, 3, 49, 44, 700000, 6600000)},
+		{3034, "ETRS89 / LCC Europe", DatumETRS89,
+			NewLambertConformalConic2SP(GRS80, 52, 10, 35, 65, 4000000, 2800000)},
+		{3978, "NAD83 / Canada Atlas Lambert", DatumNAD83,
+			NewLambertConformalConic2SP(GRS80, 49, -95, 49, 77, 0, 0)},
+		{3347, "NAD83 / Statistics Canada Lambert", DatumNAD83,
+			NewLambertConformalConic2SP(GRS80, 63.390675, -91.86666666666666, 49, 77, 6200000, 3000000)},
+		{2193, "NZGD2000 / New Zealand Transverse Mercator 2000", DatumNZGD2K,
+			NewTransverseMercator(GRS80, 0, 173, 0.9996, 1600000, 10000000)},
+		{3006, "SWEREF99 TM", DatumSWEREF,
+			NewTransverseMercator(GRS80, 0, 15, 0.9996, 500000, 0)},
+		{2180, "ETRS89 / Poland CS92", DatumETRS89,
+			NewTransverseMercator(GRS80, 0, 19, 0.9993, 500000, -5300000)},
+		{3067, "ETRS89 / TM35FIN(E,N)", DatumETRS89,
+			NewTransverseMercator(GRS80, 0, 27, 0.9996, 500000, 0)},
+	} {
+		Register(c)
+	}
+	registerUTM(32600, "WGS 84 / UTM zone %dN", DatumWGS84, 1, 60, false)
+	registerUTM(32700, "WGS 84 / UTM zone %dS", DatumWGS84, 1, 60, true)
+	registerUTM(25800, "ETRS89 / UTM zone %dN", DatumETRS89, 28, 38, false)
+	registerUTM(26900, "NAD83 / UTM zone %dN", DatumNAD83, 1, 23, false)
+	registerUTM(26700, "NAD27 / UTM zone %dN", DatumNAD27, 1, 22, false)
+	registerUTM(23000, "ED50 / UTM zone %dN", DatumED50, 28, 38, false)
+	registerUTM(28300, "GDA94 / MGA zone %d", DatumGDA94, 48, 58, true)
+	registerUTM(7800, "GDA2020 / MGA zone %d", DatumGDA20, 46, 59, true)
+	for zone := 2; zone <= 5; zone++ {
+		Register(CRS{31464 + zone, fmt.Sprintf("DHDN / 3-degree Gauss-Kruger zone %d", zone), DatumDHDN,
+			NewTransverseMercator(Bessel1841, 0, float64(zone*3), 1, float64(zone)*1e6+500000, 0)})
+	}
+}
+
+// northingFirst lists the registered projected CRS whose EPSG axis order is
+// northing, easting.
+var northingFirst = map[int]bool{
+	2180:  true, // Poland CS92
+	2193:  true, // NZTM2000
+	3006:  true, // SWEREF99 TM
+	3034:  true, // LCC Europe
+	31466: true, // DHDN / Gauss-Kruger zones 2 to 5
+	31467: true,
+	31468: true,
+	31469: true,
+}
+
+// registerUTM registers the UTM zones from..to as base+zone. name is a
+// format string taking the zone number.
+func registerUTM(base int, name string, d Datum, from, to int, south bool) {
+	for zone := from; zone <= to; zone++ {
+		Register(CRS{base + zone, fmt.Sprintf(name, zone), d, UTM(d.Ellipsoid, zone, south)})
+	}
+}
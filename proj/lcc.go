@@ -0,0 +1,98 @@
+package proj
+
+import "math"
+
+// lambertConformalConic is the ellipsoidal Lambert conformal conic
+// projection as given by Snyder.
+type lambertConformalConic struct {
+	params         []wktParam
+	e, a           float64
+	n, f, rho0     float64
+	lam0           float64
+	falseE, falseN float64
+}
+
+// NewLambertConformalConic2SP returns a Lambert conformal conic Projection
+// with two standard parallels lat1 and lat2 on ellipsoid el. lat0, lon0 is
+// the false origin, all angles in degrees.
+func NewLambertConformalConic2SP(el Ellipsoid, lat0, lon0, lat1, lat2, falseE, falseN float64) Projection {
+	l := &lambertConformalConic{e: math.Sqrt(el.e2()), a: el.A, lam0: lon0 * deg, falseE: falseE, falseN: falseN}
+	l.params = []wktParam{
+		{"standard_parallel_1", lat1},
+		{"standard_parallel_2", lat2},
+		{"latitude_of_origin", lat0},
+		{"central_meridian", lon0},
+		{"false_easting", falseE},
+		{"false_northing", falseN},
+	}
+	phi1, phi2 := lat1*deg, lat2*deg
+	m1, m2 := l.m(phi1), l.m(phi2)
+	t1, t2 := l.t(phi1), l.t(phi2)
+	if math.Abs(phi1-phi2) < 1e-10 {
+		l.n = math.Sin(phi1)
+	} else {
+		l.n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	l.f = m1 / (l.n * math.Pow(t1, l.n))
+	l.rho0 = l.a * l.f * math.Pow(l.t(lat0*deg), l.n)
+	return l
+}
+
+// NewLambertConformalConic1SP returns a Lambert conformal conic Projection
+// with the single standard parallel lat0 and scale factor k0 on ellipsoid el.
+func NewLambertConformalConic1SP(el Ellipsoid, lat0, lon0, k0, falseE, falseN float64) Projection {
+	l := &lambertConformalConic{e: math.Sqrt(el.e2()), a: el.A * k0, lam0: lon0 * deg, falseE: falseE, falseN: falseN}
+	l.params = []wktParam{
+		{"latitude_of_origin", lat0},
+		{"central_meridian", lon0},
+		{"scale_factor", k0},
+		{"false_easting", falseE},
+		{"false_northing", falseN},
+	}
+	phi0 := lat0 * deg
+	t0 := l.t(phi0)
+	l.n = math.Sin(phi0)
+	l.f = l.m(phi0) / (l.n * math.Pow(t0, l.n))
+	l.rho0 = l.a * l.f * math.Pow(t0, l.n)
+	return l
+}
+
+func (l *lambertConformalConic) m(phi float64) float64 {
+	s := math.Sin(phi)
+	return math.Cos(phi) / math.Sqrt(1-l.e*l.e*s*s)
+}
+
+func (l *lambertConformalConic) t(phi float64) float64 {
+	s := math.Sin(phi)
+	return math.Tan(math.Pi/4-phi/2) / math.Pow((1-l.e*s)/(1+l.e*s), l.e/2)
+}
+
+// Forward implements Projection.
+func (l *lambertConformalConic) Forward(lon, lat float64) (float64, float64) {
+	rho := l.a * l.f * math.Pow(l.t(lat*deg), l.n)
+	theta := l.n * (lon*deg - l.lam0)
+	return l.falseE + rho*math.Sin(theta), l.falseN + l.rho0 - rho*math.Cos(theta)
+}
+
+// Inverse implements Projection.
+func (l *lambertConformalConic) Inverse(x, y float64) (float64, float64) {
+	dx, dy := x-l.falseE, l.rho0-(y-l.falseN)
+	sign := 1.0
+	if l.n < 0 {
+		sign = -1
+	}
+	rho := sign * math.Hypot(dx, dy)
+	theta := math.Atan2(sign*dx, sign*dy)
+	t := math.Pow(rho/(l.a*l.f), 1/l.n)
+	phi := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		s := l.e * math.Sin(phi)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-s)/(1+s), l.e/2))
+		if math.Abs(next-phi) < 1e-12 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+	return (theta/l.n + l.lam0) / deg, phi / deg
+}
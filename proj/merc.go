@@ -0,0 +1,21 @@
+package proj
+
+import "math"
+
+// webMercatorMaxLat is the latitude at which Web Mercator becomes square.
+const webMercatorMaxLat = 85.0511287798066
+
+// webMercator is the spherical "Pseudo-Mercator" used by web maps, EPSG:3857.
+type webMercator struct{}
+
+// Forward implements Projection. Latitudes beyond the square world extent
+// are clamped.
+func (webMercator) Forward(lon, lat float64) (float64, float64) {
+	lat = math.Max(-webMercatorMaxLat, math.Min(webMercatorMaxLat, lat))
+	return WGS84.A * lon * deg, WGS84.A * math.Log(math.Tan(math.Pi/4+lat*deg/2))
+}
+
+// Inverse implements Projection.
+func (webMercator) Inverse(x, y float64) (float64, float64) {
+	return x / WGS84.A / deg, (2*math.Atan(math.Exp(y/WGS84.A)) - math.Pi/2) / deg
+}
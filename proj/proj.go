@@ -0,0 +1,104 @@
+// Package proj provides pure Go transformations between the geographic and
+// projected coordinate reference systems commonly offered by WFS3 services.
+//
+// Only the projections needed for everyday web and national grid use are
+// implemented: Web Mercator, transverse Mercator (including UTM) and Lambert
+// conformal conic. Datum changes use a 7 parameter Helmert transformation so
+// results for datums other than WGS 84 and its realizations are accurate to a
+// few meters, not to survey grade.
+package proj
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const deg = math.Pi / 180
+
+// Projection converts between geographic coordinates in degrees and
+// projected coordinates in meters.
+type Projection interface {
+	Forward(lon, lat float64) (x, y float64)
+	Inverse(x, y float64) (lon, lat float64)
+}
+
+// CRS is a coordinate reference system. A nil Projection denotes a
+// geographic CRS with coordinates in longitude, latitude degrees.
+type CRS struct {
+	Code       int
+	Name       string
+	Datum      Datum
+	Projection Projection
+}
+
+// Geographic reports whether the CRS is not projected.
+func (c CRS) Geographic() bool {
+	return c.Projection == nil
+}
+
+// NorthingFirst reports whether the EPSG axis order of the CRS puts latitude
+// or northing first. Coordinates in this package are always x, y.
+func (c CRS) NorthingFirst() bool {
+	return c.Geographic() || northingFirst[c.Code]
+}
+
+var registry = struct {
+	sync.RWMutex
+	crs map[int]CRS
+}{crs: map[int]CRS{}}
+
+// Register adds or replaces the CRS under its Code.
+func Register(c CRS) {
+	registry.Lock()
+	registry.crs[c.Code] = c
+	registry.Unlock()
+}
+
+// Lookup returns the CRS registered for the EPSG code.
+func Lookup(code int) (CRS, error) {
+	registry.RLock()
+	c, ok := registry.crs[code]
+	registry.RUnlock()
+	if !ok {
+		return CRS{}, fmt.Errorf("unsupported CRS EPSG:%d", code)
+	}
+	return c, nil
+}
+
+// Transformer converts coordinates from one CRS to another.
+type Transformer struct {
+	Src, Dst CRS
+}
+
+// NewTransformer looks up both EPSG codes and returns a Transformer between
+// them.
+func NewTransformer(src, dst int) (Transformer, error) {
+	s, err := Lookup(src)
+	if err != nil {
+		return Transformer{}, err
+	}
+	d, err := Lookup(dst)
+	if err != nil {
+		return Transformer{}, err
+	}
+	return Transformer{s, d}, nil
+}
+
+// Transform converts a single coordinate. Geographic coordinates are in
+// longitude, latitude order.
+func (t Transformer) Transform(x, y float64) (float64, float64) {
+	if t.Src.Code == t.Dst.Code {
+		return x, y
+	}
+	lon, lat := x, y
+	if !t.Src.Geographic() {
+		lon, lat = t.Src.Projection.Inverse(x, y)
+	}
+	lam, phi := t.Src.Datum.shift(t.Dst.Datum, lon*deg, lat*deg)
+	lon, lat = lam/deg, phi/deg
+	if t.Dst.Geographic() {
+		return lon, lat
+	}
+	return t.Dst.Projection.Forward(lon, lat)
+}
@@ -0,0 +1,92 @@
+package proj
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestTransverseMercator(t *testing.T) {
+	// EPSG guidance note 7-2 example for the British National Grid
+	bng := NewTransverseMercator(Airy1830, 49, -2, 0.9996012717, 400000, -100000)
+	x, y := bng.Forward(0.5, 50.5)
+	if !near(x, 577274.99, 0.01) || !near(y, 69740.50, 0.01) {
+		t.Errorf("forward %f %f", x, y)
+	}
+	lon, lat := bng.Inverse(x, y)
+	if !near(lon, 0.5, 1e-9) || !near(lat, 50.5, 1e-9) {
+		t.Errorf("inverse %f %f", lon, lat)
+	}
+}
+
+func TestLambertConformalConic(t *testing.T) {
+	// EPSG guidance note 7-2 example for Texas South Central, converted from
+	// US survey feet
+	const ft = 1200.0 / 3937
+	lcc := NewLambertConformalConic2SP(Clarke1866, 27+50.0/60, -99, 28+23.0/60, 30+17.0/60, 2000000*ft, 0)
+	x, y := lcc.Forward(-96, 28.5)
+	if !near(x, 2963503.91*ft, 0.01) || !near(y, 254759.80*ft, 0.01) {
+		t.Errorf("forward %f %f", x/ft, y/ft)
+	}
+	lon, lat := lcc.Inverse(x, y)
+	if !near(lon, -96, 1e-9) || !near(lat, 28.5, 1e-9) {
+		t.Errorf("inverse %f %f", lon, lat)
+	}
+}
+
+func TestWebMercator(t *testing.T) {
+	x, y := webMercator{}.Forward(180, webMercatorMaxLat)
+	if !near(x, 20037508.34, 0.01) || !near(y, 20037508.34, 0.01) {
+		t.Errorf("forward %f %f", x, y)
+	}
+}
+
+func TestTransformer(t *testing.T) {
+	// Caister water tower, from the Ordnance Survey coordinate guide. The
+	// Helmert transformation is only good to a few meters.
+	tr, err := NewTransformer(4326, 27700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := tr.Transform(1.716073973, 52.658007833)
+	if !near(x, 651409.903, 5) || !near(y, 313177.270, 5) {
+		t.Errorf("forward %f %f", x, y)
+	}
+	for _, code := range []int{3857, 32633, 32733, 25832, 2154, 31467, 3978} {
+		tr, err := NewTransformer(4326, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// heights are dropped between datums, costing a few centimeters
+		back := Transformer{tr.Dst, tr.Src}
+		lon, lat := back.Transform(tr.Transform(12.5, -41.9))
+		if !near(lon, 12.5, 1e-6) || !near(lat, -41.9, 1e-6) {
+			t.Errorf("EPSG:%d roundtrip %f %f", code, lon, lat)
+		}
+	}
+	if _, err := Lookup(1); err == nil {
+		t.Errorf("expected unknown CRS error")
+	}
+}
+
+func TestWKT(t *testing.T) {
+	c, _ := Lookup(32632)
+	wkt, err := c.WKT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `PROJCS["WGS 84 / UTM zone 32N",GEOGCS["WGS 1984",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],` +
+		`PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],` +
+		`PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",9],PARAMETER["scale_factor",0.9996],` +
+		`PARAMETER["false_easting",500000],PARAMETER["false_northing",0],UNIT["metre",1],AUTHORITY["EPSG","32632"]]`
+	if wkt != expected {
+		t.Errorf("wkt %s", wkt)
+	}
+	c, _ = Lookup(4326)
+	if wkt, _ := c.WKT(); wkt != `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4326"]]` {
+		t.Errorf("wkt %s", wkt)
+	}
+}
@@ -0,0 +1,97 @@
+package proj
+
+import "math"
+
+// transverseMercator is the ellipsoidal transverse Mercator projection using
+// the Krüger series to fourth order in n, accurate to well below a millimeter
+// within a few degrees of the central meridian.
+type transverseMercator struct {
+	lat0, lon0         float64
+	k0, falseE, falseN float64
+	e, a               float64
+	alpha, beta, delta [4]float64
+	lam0, m0           float64
+}
+
+// NewTransverseMercator returns a transverse Mercator Projection on ellipsoid
+// el with the natural origin at lat0, lon0 in degrees, the scale factor k0
+// on the central meridian and the false easting and northing in meters.
+func NewTransverseMercator(el Ellipsoid, lat0, lon0, k0, falseE, falseN float64) Projection {
+	t := &transverseMercator{lat0: lat0, lon0: lon0, k0: k0, falseE: falseE, falseN: falseN}
+	f := el.F
+	n := f / (2 - f)
+	n2, n3, n4 := n*n, n*n*n, n*n*n*n
+	t.e = math.Sqrt(el.e2())
+	t.a = el.A / (1 + n) * (1 + n2/4 + n4/64)
+	t.alpha = [4]float64{
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180,
+		13*n2/48 - 3*n3/5 + 557*n4/1440,
+		61*n3/240 - 103*n4/140,
+		49561 * n4 / 161280,
+	}
+	t.beta = [4]float64{
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360,
+		n2/48 + n3/15 - 437*n4/1440,
+		17*n3/480 - 37*n4/840,
+		4397 * n4 / 161280,
+	}
+	t.delta = [4]float64{
+		2*n - 2*n2/3 - 2*n3 + 116*n4/45,
+		7*n2/3 - 8*n3/5 - 227*n4/45,
+		56*n3/15 - 136*n4/35,
+		4279 * n4 / 630,
+	}
+	t.lam0 = lon0 * deg
+	_, t.m0 = t.project(t.lam0, lat0*deg)
+	return t
+}
+
+// UTM returns the Projection of the given UTM zone on ellipsoid el.
+func UTM(el Ellipsoid, zone int, south bool) Projection {
+	falseN := 0.0
+	if south {
+		falseN = 10000000
+	}
+	return NewTransverseMercator(el, 0, float64(zone)*6-183, 0.9996, 500000, falseN)
+}
+
+// project returns the unscaled and unshifted easting and northing.
+func (t *transverseMercator) project(lam, phi float64) (float64, float64) {
+	sp := math.Sin(phi)
+	tau := math.Sinh(math.Atanh(sp) - t.e*math.Atanh(t.e*sp))
+	dl := lam - t.lam0
+	xi := math.Atan2(tau, math.Cos(dl))
+	eta := math.Atanh(math.Sin(dl) / math.Sqrt(1+tau*tau))
+	x, y := eta, xi
+	for j, a := range t.alpha {
+		k := 2 * float64(j+1)
+		x += a * math.Cos(k*xi) * math.Sinh(k*eta)
+		y += a * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	return t.a * x, t.a * y
+}
+
+// Forward implements Projection.
+func (t *transverseMercator) Forward(lon, lat float64) (float64, float64) {
+	x, y := t.project(lon*deg, lat*deg)
+	return t.falseE + t.k0*x, t.falseN + t.k0*(y-t.m0)
+}
+
+// Inverse implements Projection.
+func (t *transverseMercator) Inverse(x, y float64) (float64, float64) {
+	xi := ((y-t.falseN)/t.k0 + t.m0) / t.a
+	eta := (x - t.falseE) / t.k0 / t.a
+	xp, ep := xi, eta
+	for j, b := range t.beta {
+		k := 2 * float64(j+1)
+		xp -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		ep -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xp) / math.Cosh(ep))
+	phi := chi
+	for j, d := range t.delta {
+		phi += d * math.Sin(2*float64(j+1)*chi)
+	}
+	lam := t.lam0 + math.Atan2(math.Sinh(ep), math.Cos(xp))
+	return lam / deg, phi / deg
+}
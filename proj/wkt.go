@@ -0,0 +1,95 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type wktParam struct {
+	name  string
+	value float64
+}
+
+// wktProjection is implemented by projections that can describe themselves
+// as an OGC WKT PROJECTION and its PARAMETERs.
+type wktProjection interface {
+	wkt() (string, []wktParam)
+}
+
+func (t *transverseMercator) wkt() (string, []wktParam) {
+	return "Transverse_Mercator", []wktParam{
+		{"latitude_of_origin", t.lat0},
+		{"central_meridian", t.lon0},
+		{"scale_factor", t.k0},
+		{"false_easting", t.falseE},
+		{"false_northing", t.falseN},
+	}
+}
+
+func (l *lambertConformalConic) wkt() (string, []wktParam) {
+	if len(l.params) == 6 {
+		return "Lambert_Conformal_Conic_2SP", l.params
+	}
+	return "Lambert_Conformal_Conic_1SP", l.params
+}
+
+func (webMercator) wkt() (string, []wktParam) {
+	return "Mercator_1SP", []wktParam{
+		{"central_meridian", 0},
+		{"scale_factor", 1},
+		{"false_easting", 0},
+		{"false_northing", 0},
+	}
+}
+
+func wktNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// geogcs returns the WKT of the geographic CRS underlying d.
+func (d Datum) geogcs(name string, code int) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `GEOGCS["%s",DATUM["%s",SPHEROID["%s",%s,%s]`, name, d.Name,
+		d.Ellipsoid.Name, wktNumber(d.Ellipsoid.A), wktNumber(math.Round(1e9/d.Ellipsoid.F)/1e9))
+	if d.ToWGS84 != [7]float64{} {
+		p := make([]string, len(d.ToWGS84))
+		for i, v := range d.ToWGS84 {
+			p[i] = wktNumber(v)
+		}
+		fmt.Fprintf(b, `,TOWGS84[%s]`, strings.Join(p, ","))
+	}
+	b.WriteString(`],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]`)
+	if code != 0 {
+		fmt.Fprintf(b, `,AUTHORITY["EPSG","%d"]`, code)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// WKT returns the OGC WKT (version 1) definition of the CRS. An error is
+// returned for projections that cannot be described.
+func (c CRS) WKT() (string, error) {
+	if c.Geographic() {
+		return c.Datum.geogcs(c.Name, c.Code), nil
+	}
+	wp, ok := c.Projection.(wktProjection)
+	if !ok {
+		return "", fmt.Errorf("no WKT for %s", c.Name)
+	}
+	method, params := wp.wkt()
+	b := &strings.Builder{}
+	geogName := strings.Replace(c.Datum.Name, "_", " ", -1)
+	fmt.Fprintf(b, `PROJCS["%s",%s,PROJECTION["%s"]`, c.Name, c.Datum.geogcs(geogName, 0), method)
+	for _, p := range params {
+		fmt.Fprintf(b, `,PARAMETER["%s",%s]`, p.name, wktNumber(p.value))
+	}
+	b.WriteString(`,UNIT["metre",1]`)
+	if _, ok := c.Projection.(webMercator); ok {
+		// spherical Mercator on the WGS 84 datum needs the PROJ.4 escape hatch
+		b.WriteString(`,EXTENSION["PROJ4","+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext +no_defs"]`)
+	}
+	fmt.Fprintf(b, `,AUTHORITY["EPSG","%d"]]`, c.Code)
+	return b.String(), nil
+}
@@ -1,38 +1,65 @@
 package wfs
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/jban332/kin-openapi/openapi3"
 )
 
 // Service represents a single WFS3 service.
 type Service struct {
-	cl    Client
-	spec  *openapi3.Swagger
-	paths pather
+	cl      Client
+	spec    *openapi3.Swagger
+	paths   pather
+	specURL string
+	patches PatchReport
+	// base overrides the server URL of the spec, if set
+	base string
+	// server is the selected server of the spec, resolved when connecting
+	server string
+	ops    *operationIndex
 }
 
 // ServiceInfo is a high-level summary of the service.
 type ServiceInfo struct {
 	URL         string
 	Description string
+	// PathStyle is the path convention used with the service, "oldStyle" or
+	// "newStyle".
+	PathStyle string
+	// SpecURL is the URL the OpenAPI document was read from.
+	SpecURL string
+}
+
+// PatchReport lists the component references of the spec that were not
+// defined in it and those patched in when connecting.
+func (s Service) PatchReport() PatchReport {
+	return s.patches
 }
 
 // Info returns the ServiceInfo.
 func (s Service) Info() ServiceInfo {
 	url := "undefined"
-	if len(s.spec.Servers) > 0 {
-		url = s.spec.Servers[0].URL
+	if s.base != "" {
+		url = s.base
+	} else if s.server != "" {
+		url = s.server
 	}
 	return ServiceInfo{
 		url,
 		s.spec.Info.Description,
+		string(s.paths.style),
+		s.specURL,
 	}
 }
 
@@ -47,49 +74,88 @@ func (s Service) DescribeCollections() (Operation, error) {
 	return Operation{}, fmt.Errorf("No describeCollections operation defined")
 }
 
-// Operations returns all supported Operations.
+// Operations returns all supported Operations, sorted by path and method.
 func (s Service) Operations() []Operation {
-	paths := s.spec.Paths
-	ops := []Operation{}
-	for k, path := range paths {
-		for _, op := range path.Operations() {
-			ops = append(ops, s.operationFromSwagger(k, op))
-		}
-	}
-	return ops
+	return append([]Operation{}, s.operations().ops...)
 }
 
-func (s Service) operationFromSwagger(path string, op *openapi3.Operation) Operation {
+func (s Service) operationFromSwagger(path, method string, op *openapi3.Operation) Operation {
 	params := []Parameter{}
-	for _, p := range op.Parameters {
-		pv := p.Value
-		params = append(params, Parameter{
-			p:           pv,
-			Description: pv.Description,
-			Name:        pv.Name,
-			Required:    pv.Required,
-			Type:        pv.Schema.Value.Type,
-		})
+	declared := map[string]bool{}
+	// parameters of the path apply unless the operation redeclares them
+	for _, refs := range []openapi3.Parameters{op.Parameters, s.spec.Paths[path].Parameters} {
+		for _, p := range refs {
+			if p == nil || p.Value == nil || declared[p.Value.In+" "+p.Value.Name] {
+				continue
+			}
+			declared[p.Value.In+" "+p.Value.Name] = true
+			params = append(params, parameterFromSwagger(p.Value))
+		}
+	}
+	content := map[string]bool{}
+	for status, r := range op.Responses {
+		if r == nil || r.Value == nil || !(strings.HasPrefix(status, "2") || status == "default") {
+			continue
+		}
+		for t := range r.Value.Content {
+			content[t] = true
+		}
+	}
+	var body *RequestBody
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		rb := op.RequestBody.Value
+		body = &RequestBody{b: rb, Description: rb.Description, Required: rb.Required}
+		for t := range rb.Content {
+			body.ContentTypes = append(body.ContentTypes, t)
+		}
+		sort.Strings(body.ContentTypes)
 	}
+	// servers declared by the operation override those of its path
+	servers := s.spec.Paths[path].Servers
+	if op.Servers != nil && len(*op.Servers) > 0 {
+		servers = *op.Servers
+	}
+	server, serverErr := s.cl.servers.resolve(servers, false, s.serverReference())
 	return Operation{
 		svc:         s,
+		server:      server,
+		serverErr:   serverErr,
 		Description: op.Description,
 		ID:          op.OperationID,
+		Tags:        op.Tags,
+		Method:      method,
 		Path:        path,
 		Params:      params,
+		Body:        body,
+		mediaTypes:  responseMediaTypes(content),
+	}
+}
+
+// parameterFromSwagger converts pv, whose schema may be missing when it
+// declares content instead.
+func parameterFromSwagger(pv *openapi3.Parameter) Parameter {
+	p := Parameter{
+		p:           pv,
+		Description: pv.Description,
+		Name:        pv.Name,
+		In:          pv.In,
+		Required:    pv.Required,
+	}
+	if pv.Schema != nil && pv.Schema.Value != nil {
+		p.Type = pv.Schema.Value.Type
+		p.Enum = pv.Schema.Value.Enum
+		p.Default = pv.Schema.Value.Default
 	}
+	return p
 }
 
 // GetOperation returns an Operation by ID. An error is returned
-// if not found.
+// if not found. Operations lacking an ID or repeating that of another are
+// found by the ID synthesized from their method and path.
 func (s Service) GetOperation(id string) (Operation, error) {
-	paths := s.spec.Paths
-	for k, path := range paths {
-		for _, op := range path.Operations() {
-			if op.OperationID == id {
-				return s.operationFromSwagger(k, op), nil
-			}
-		}
+	idx := s.operations()
+	if i, ok := idx.byID[id]; ok {
+		return idx.ops[i], nil
 	}
 	return Operation{}, fmt.Errorf("no operation %q", id)
 }
@@ -98,9 +164,21 @@ func (s Service) GetOperation(id string) (Operation, error) {
 type Operation struct {
 	svc         Service
 	Description string
-	ID          string
-	Path        string
-	Params      []Parameter
+	// ID is the operationId of the spec or, if missing or not unique, one
+	// derived from the method and path.
+	ID   string
+	Tags []string
+	// Method is the HTTP method of the Operation, in upper case.
+	Method string
+	Path   string
+	Params []Parameter
+	// Body is the request body accepted by the Operation, nil if none.
+	Body       *RequestBody
+	mediaTypes []MediaType
+	// server overrides the service URL when the path or operation declares
+	// servers of its own
+	server    string
+	serverErr error
 }
 
 func findParameter(params []Parameter, name string) (Parameter, bool) {
@@ -115,17 +193,28 @@ func findParameter(params []Parameter, name string) (Parameter, bool) {
 
 // URL returns the full URL of the Operation.
 func (o Operation) URL() string {
-	return o.svc.Info().URL + o.Path
+	if o.server != "" {
+		return strings.TrimSuffix(o.server, "/") + o.Path
+	}
+	return strings.TrimSuffix(o.svc.Info().URL, "/") + o.Path
+}
+
+// MediaTypes returns the media types declared by the success responses of
+// the Operation. Types that are not registered in MediaTypes have an empty
+// Short name and no Decoder.
+func (o Operation) MediaTypes() []MediaType {
+	return o.mediaTypes
 }
 
 // SimpleCall returns a Call that has no parameters.
 func (o Operation) SimpleCall() Call {
-	return Call{o, nil, "json"}
+	return Call{op: o}
 }
 
 // Call returns a Call with parameters as provided by the given map. An
 // error is returned if any parameter key is not found.
-// The default response format is configured as "json".
+// The response formats default to those declared by the Operation, or
+// "json" if it declares none.
 func (o Operation) Call(params map[string]interface{}) (Call, error) {
 	pv := []parameterValue{}
 	for k, v := range params {
@@ -135,49 +224,235 @@ func (o Operation) Call(params map[string]interface{}) (Call, error) {
 		}
 		pv = append(pv, parameterValue{p, v})
 	}
-	return Call{o, pv, "json"}, nil
+	return Call{op: o, params: pv}, nil
 }
 
 // Call represents a pending invocation of an Operation.
 type Call struct {
-	op        Operation
-	params    []parameterValue
-	mediaType string
+	op          Operation
+	params      []parameterValue
+	accept      []string
+	body        []byte
+	contentType string
+	ctx         context.Context
 }
 
-// Accept returns a Call that will use the provided media type.
-func (c Call) Accept(mediaType string) Call {
-	c.mediaType = mediaType
+// WithContext returns a Call whose request is made with ctx.
+func (c Call) WithContext(ctx context.Context) Call {
+	c.ctx = ctx
 	return c
 }
 
-// ExecuteWriter will invoke the Call operation writing the response to the
-// provided io.Writer.
+// WithBody returns a Call sending body as the request body. contentType may
+// be empty if the Operation declares a single one. An error is returned if
+// the Operation takes no body, does not accept contentType, or, for JSON
+// types, if body does not validate against the declared schema.
+func (c Call) WithBody(contentType string, body []byte) (Call, error) {
+	rb := c.op.Body
+	if rb == nil {
+		return Call{}, fmt.Errorf("operation %s takes no request body", c.op.ID)
+	}
+	if contentType == "" {
+		if len(rb.ContentTypes) != 1 {
+			return Call{}, fmt.Errorf("operation %s accepts %s, choose a content type", c.op.ID, strings.Join(rb.ContentTypes, ", "))
+		}
+		contentType = rb.ContentTypes[0]
+	}
+	if mt := MediaTypes.LookupShort(contentType); mt.Full != "" {
+		contentType = mt.Full
+	}
+	if err := rb.validate(contentType, body); err != nil {
+		return Call{}, fmt.Errorf("invalid request body for %s : %s", c.op.ID, err)
+	}
+	c.body, c.contentType = body, contentType
+	return c, nil
+}
+
+// Accept returns a Call that will request the provided media types, given
+// by short or full name in order of preference.
+func (c Call) Accept(mediaTypes ...string) Call {
+	c.accept = nil
+	for _, t := range mediaTypes {
+		if t != "" {
+			c.accept = append(c.accept, t)
+		}
+	}
+	return c
+}
+
+// mediaTypes resolves the accepted media types. Types the Operation does not
+// declare are rejected.
+func (c Call) mediaTypes() ([]MediaType, error) {
+	declared := c.op.mediaTypes
+	if len(c.accept) == 0 {
+		if len(declared) == 0 {
+			return []MediaType{MediaTypes.LookupShort("json")}, nil
+		}
+		return declared, nil
+	}
+	types := []MediaType{}
+	for _, name := range c.accept {
+		mt := MediaTypes.Lookup(name)
+		if mt.Full == "" {
+			mt = mediaTypes(declared).Lookup(name)
+		}
+		if mt.Full == "" {
+			return nil, fmt.Errorf("No Media Type: %s", name)
+		}
+		if len(declared) > 0 && mediaTypes(declared).Lookup(mt.Full).Full == "" {
+			return nil, fmt.Errorf("operation %s does not produce %s", c.op.ID, mt.Full)
+		}
+		types = append(types, mt)
+	}
+	return types, nil
+}
+
+// ExecuteWriter will invoke the Call operation with its HTTP method writing
+// the response to the provided io.Writer. An error is returned if the
+// response is not of one of the accepted media types. Nothing is written for
+// a 204 No Content response.
 func (c Call) ExecuteWriter(w io.Writer) error {
-	req, err := c.buildRequest()
+	req, types, err := c.request()
+	if err != nil {
+		return err
+	}
+	return c.op.svc.cl.doWriter(req, w, types)
+}
+
+// Decode invokes the Call and decodes the response into v with the Decoder
+// of its media type.
+func (c Call) Decode(v interface{}) error {
+	req, types, err := c.request()
 	if err != nil {
 		return err
 	}
-	mt := MediaTypes.Lookup(c.mediaType)
-	if mt.Full == "" {
-		return fmt.Errorf("No Media Type: %s", c.mediaType)
+	data, header, err := c.op.svc.cl.fetch(req)
+	if err != nil {
+		return err
+	}
+	ct := header.Get("Content-Type")
+	if !matchesContentType(ct, types) {
+		return &ContentTypeError{req.URL.String(), ct, req.Header.Get("Accept")}
+	}
+	if ct == "" {
+		ct = types[0].Full
+	}
+	mt := MediaTypes.Lookup(ct)
+	if mt.Decode == nil {
+		return fmt.Errorf("no decoder for %s", ct)
+	}
+	return mt.Decode(data, v)
+}
+
+// request builds the request with the Accept header and, if the Operation
+// has an "f" query parameter that was not given, the matching format.
+func (c Call) request() (*http.Request, []MediaType, error) {
+	types, err := c.mediaTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if f, ok := findParameter(c.op.Params, "f"); ok && f.p.In == "query" && !c.hasParam("f") && f.allows(types[0].Short) {
+		c.params = append(c.params, parameterValue{f, types[0].Short})
+	}
+	req, err := c.buildRequest()
+	if err != nil {
+		return nil, nil, err
 	}
-	if c.mediaType != "" {
-		req.Header.Set("Accept", mt.Full)
+	req.Header.Set("Accept", acceptHeader(types))
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+	return req, types, nil
+}
+
+func (c Call) hasParam(name string) bool {
+	for _, pv := range c.params {
+		if pv.Def.Name == name {
+			return true
+		}
 	}
-	return c.op.svc.cl.doWriter(req, w)
+	return false
 }
 
 func (c Call) buildRequest() (*http.Request, error) {
-	url := c.op.URL()
+	if c.op.serverErr != nil {
+		return nil, c.op.serverErr
+	}
+	u := c.op.URL()
+	query := url.Values{}
 	for _, pv := range c.params {
-		if pv.Def.p.In == "path" {
-			url = strings.Replace(url, fmt.Sprintf("{%s}", pv.Def.p.Name), fmt.Sprint(pv.Value), -1)
-		} else {
+		switch pv.Def.p.In {
+		case "path":
+			u = strings.Replace(u, fmt.Sprintf("{%s}", pv.Def.p.Name), fmt.Sprint(pv.Value), -1)
+		case "query":
+			query.Add(pv.Def.Name, fmt.Sprint(pv.Value))
+		default:
 			return nil, fmt.Errorf("paramter in %s not supported for %s", pv.Def.p.In, pv.Def.Name)
 		}
 	}
-	return http.NewRequest("GET", url, nil)
+	method := c.op.Method
+	if method == "" {
+		method = "GET"
+	}
+	if c.body == nil && c.op.Body != nil && c.op.Body.Required {
+		return nil, fmt.Errorf("operation %s requires a request body", c.op.ID)
+	}
+	var body io.Reader
+	if c.body != nil {
+		body = bytes.NewReader(c.body)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.body != nil {
+		req.Header.Set("Content-Type", c.contentType)
+	}
+	if len(query) > 0 {
+		req.URL.RawQuery = query.Encode()
+	}
+	return req, nil
+}
+
+// RequestBody describes the body accepted by an Operation.
+type RequestBody struct {
+	b            *openapi3.RequestBody
+	Description  string
+	Required     bool
+	ContentTypes []string
+}
+
+// validate checks that contentType is declared and that JSON bodies match
+// the declared schema. Wildcard declarations such as "*/*" accept any type.
+func (r *RequestBody) validate(contentType string, body []byte) error {
+	var declared *openapi3.MediaType
+	base := mediaBase(contentType)
+	for t, mt := range r.b.Content {
+		tb := mediaBase(t)
+		if tb == base || tb == "*/*" || (strings.HasSuffix(tb, "/*") && strings.HasPrefix(base, strings.TrimSuffix(tb, "*"))) {
+			declared = mt
+			if tb == base {
+				break
+			}
+		}
+	}
+	if declared == nil {
+		return fmt.Errorf("content type %s not accepted, expected one of %s", contentType, strings.Join(r.ContentTypes, ", "))
+	}
+	if !isJSON(base) || declared.Schema == nil || declared.Schema.Value == nil {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return err
+	}
+	return declared.Schema.Value.VisitJSON(v)
+}
+
+// isJSON reports whether the media type t, without parameters, is JSON or a
+// JSON based type such as application/geo+json.
+func isJSON(t string) bool {
+	return t == "application/json" || strings.HasSuffix(t, "+json")
 }
 
 // Parameter represents an optional or mandatory argument to an Operation.
@@ -185,8 +460,30 @@ type Parameter struct {
 	p           *openapi3.Parameter
 	Description string
 	Name        string
-	Required    bool
-	Type        string
+	// In is the location of the parameter: path, query, header or cookie.
+	In       string
+	Required bool
+	Type     string
+	// Enum lists the allowed values, empty if any value is allowed.
+	Enum    []interface{}
+	Default interface{}
+}
+
+// allows reports whether v is one of the enumerated values of the
+// Parameter. Parameters without an enumeration allow any non-empty value.
+func (p Parameter) allows(v string) bool {
+	if v == "" {
+		return false
+	}
+	if p.p.Schema == nil || p.p.Schema.Value == nil || len(p.p.Schema.Value.Enum) == 0 {
+		return true
+	}
+	for _, e := range p.p.Schema.Value.Enum {
+		if fmt.Sprint(e) == v {
+			return true
+		}
+	}
+	return false
 }
 
 type parameterValue struct {
@@ -194,45 +491,207 @@ type parameterValue struct {
 	Value interface{}
 }
 
+// StatusError is returned when a request completes with an unexpected HTTP
+// status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http error calling %s : %d - %s", e.URL, e.StatusCode, e.Status)
+}
+
+func isNotFound(err error) bool {
+	se, ok := err.(*StatusError)
+	return ok && se.StatusCode == 404
+}
+
 // Client provides a WFS3 client.
 type Client struct {
-	client *http.Client
+	client  *http.Client
+	hosts   *hostLimiter
+	patch   *componentPatch
+	log     Logger
+	servers serverSelection
 }
 
 // NewClient creates a Client that will use the provided http.Client.
 func NewClient(cl *http.Client) Client {
-	return Client{cl}
+	return Client{client: cl}
+}
+
+// WithHostLimit returns a Client that makes at most n concurrent requests to
+// any single host. n <= 0 removes the limit.
+func (c Client) WithHostLimit(n int) Client {
+	c.hosts = nil
+	if n > 0 {
+		c.hosts = &hostLimiter{limit: n, hosts: map[string]chan struct{}{}}
+	}
+	return c
+}
+
+// WithFallbackComponents returns a Client that patches specs referencing
+// undefined components with those of the given JSON components objects,
+// tried in order, instead of the builtin WFS3 components.
+func (c Client) WithFallbackComponents(components ...[]byte) Client {
+	c.patch = &componentPatch{fallbacks: components}
+	return c
+}
+
+// WithoutPatching returns a Client that fails to connect to services whose
+// spec references undefined components.
+func (c Client) WithoutPatching() Client {
+	c.patch = &componentPatch{disabled: true}
+	return c
+}
+
+// WithLogger returns a Client writing its warnings to l rather than the
+// standard logger.
+func (c Client) WithLogger(l Logger) Client {
+	c.log = l
+	return c
+}
+
+func (c Client) logger() Logger {
+	if c.log == nil {
+		return stdLogger{}
+	}
+	return c.log
+}
+
+// hostLimiter bounds the number of concurrent requests per host.
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// acquire waits for a free slot for the host of r, returning the function
+// releasing it.
+func (h *hostLimiter) acquire(r *http.Request) (func(), error) {
+	if h == nil {
+		return func() {}, nil
+	}
+	h.mu.Lock()
+	slots, ok := h.hosts[r.URL.Host]
+	if !ok {
+		slots = make(chan struct{}, h.limit)
+		h.hosts[r.URL.Host] = slots
+	}
+	h.mu.Unlock()
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+}
+
+// send performs r within the per-host limit. The limit slot is held until
+// the response body is closed.
+func (c Client) send(r *http.Request) (*http.Response, error) {
+	release, err := c.hosts.acquire(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(r)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseCloser{resp.Body, release}
+	return resp, nil
+}
+
+type releaseCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseCloser) Close() error {
+	err := r.ReadCloser.Close()
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	return err
 }
 
 func (c Client) do(r *http.Request) ([]byte, error) {
+	bytes, _, err := c.fetch(r)
+	return bytes, err
+}
+
+// fetch works as per do but also returns the response headers.
+func (c Client) fetch(r *http.Request) ([]byte, http.Header, error) {
 	// @todo config
 	r.Header.Set("Cache-Control", "max-age=300")
-	resp, err := c.client.Do(r)
+	resp, err := c.send(r)
 	if err != nil {
-		return nil, fmt.Errorf("error calling %s : %s", r.URL, err)
+		return nil, nil, fmt.Errorf("error calling %s : %s", r.URL, err)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("http error calling %s : %d - %s", r.URL, resp.StatusCode, resp.Status)
+		return nil, nil, &StatusError{r.URL.String(), resp.StatusCode, resp.Status}
 	}
-	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	bytes, err := ioutil.ReadAll(resp.Body)
+	return bytes, resp.Header, err
 }
 
-func (c Client) doWriter(r *http.Request, w io.Writer) error {
-	resp, err := c.client.Do(r)
+// get requests the given URL with the optional query, accepting the media
+// types in order of preference.
+func (c Client) get(ctx context.Context, u string, query url.Values, accept []MediaType) ([]byte, http.Header, error) {
+	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("http error calling %s : %d - %s", r.URL, resp.StatusCode, resp.Status)
+	if len(query) > 0 {
+		req.URL.RawQuery = query.Encode()
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", acceptHeader(accept))
+	return c.fetch(req)
+}
+
+// getJSON works as per get but decodes the JSON response into v. accept is
+// the short name of the MediaType to request. The response headers are
+// returned.
+func (c Client) getJSON(ctx context.Context, u string, query url.Values, accept string, v interface{}) (http.Header, error) {
+	bytes, header, err := c.get(ctx, u, query, []MediaType{MediaTypes.LookupShort(accept)})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return nil, fmt.Errorf("error decoding %s : %s", u, err)
+	}
+	return header, nil
+}
+
+func (c Client) doWriter(r *http.Request, w io.Writer, accepted []MediaType) error {
+	resp, err := c.send(r)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{r.URL.String(), resp.StatusCode, resp.Status}
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if ct := resp.Header.Get("Content-Type"); !matchesContentType(ct, accepted) {
+		return &ContentTypeError{r.URL.String(), ct, r.Header.Get("Accept")}
+	}
 	_, err = io.Copy(w, resp.Body)
 	return err
 }
 
 // Connect will request the spec from the provided service as defined by the
-// urlRoot. oldStyle exists as a temporary toggle to switch between path
+// urlRoot. The spec is located through the service-desc link of the landing
+// page, falling back to the api path and common file names, and may be JSON
+// or YAML. oldStyle exists as a temporary toggle to switch between path
 // conventions as the spec evolves.
 func (c Client) Connect(urlRoot string, oldStyle bool) (Service, error) {
 	st := oldStylePaths
@@ -247,19 +706,55 @@ func (c Client) Connect(urlRoot string, oldStyle bool) (Service, error) {
 		return Service{}, err
 	}
 	paths := pather{u, st}
-	specURL := paths.spec()
-	req, err := http.NewRequest("GET", specURL, nil)
+	spec, specURL, patches, err := c.discoverSpec(context.Background(), paths)
 	if err != nil {
-		return Service{}, fmt.Errorf("invalid spec path: %s", specURL)
+		return Service{}, err
 	}
-	req.Header.Set("Accept", MediaTypes.LookupShort("json").Full)
-	bytes, err := c.do(req)
+	server, err := c.servers.resolve(spec.Servers, true, specURL)
 	if err != nil {
 		return Service{}, err
 	}
-	spec, err := parseSpec(bytes)
+	return c.indexed(Service{c, spec, paths, specURL, patches, "", server, nil}), nil
+}
+
+// indexed returns s with its operations indexed, warning of those whose ID
+// had to be synthesized.
+func (c Client) indexed(s Service) Service {
+	s.ops = s.indexOperations()
+	for _, p := range s.ops.problems {
+		c.logger().Printf("operation %s", p)
+	}
+	return s
+}
+
+// ConnectSpec works as per Connect but uses a spec loaded with LoadSpec or
+// ReadSpec instead of requesting it. Operations and collections are
+// requested from urlRoot; if it is empty, the server of the spec selected
+// with WithServer is used.
+func (c Client) ConnectSpec(urlRoot string, oldStyle bool, spec *Spec) (Service, error) {
+	st := oldStylePaths
+	if !oldStyle {
+		st = newStylePaths
+	}
+	base := strings.TrimSuffix(urlRoot, "/")
+	var server string
+	if urlRoot == "" {
+		var err error
+		server, err = c.servers.resolve(spec.doc.Servers, true, spec.source)
+		if err != nil {
+			return Service{}, err
+		}
+		if server == "" {
+			return Service{}, fmt.Errorf("spec %s declares no server, a URL is required", spec.source)
+		}
+		urlRoot = server
+	}
+	if !strings.HasSuffix(urlRoot, "/") {
+		urlRoot = urlRoot + "/"
+	}
+	u, err := url.Parse(urlRoot)
 	if err != nil {
 		return Service{}, err
 	}
-	return Service{c, spec, paths}, nil
+	return c.indexed(Service{c, spec.doc, pather{u, st}, spec.source, spec.patches, base, server, nil}), nil
 }
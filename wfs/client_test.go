@@ -0,0 +1,127 @@
+package wfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jban332/kin-openapi/openapi3"
+)
+
+// testService returns a Service using the new style paths of srv, with spec
+// and srv as its selected server if spec is set.
+func testService(srv *httptest.Server, spec *openapi3.Swagger) Service {
+	u, _ := url.Parse(srv.URL + "/")
+	svc := Service{cl: NewClient(srv.Client()), spec: spec, paths: pather{u, newStylePaths}}
+	if spec != nil {
+		svc.server = srv.URL
+	}
+	return svc
+}
+
+const bodySpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/processes/{processId}/jobs": {
+      "post": {
+        "operationId": "execute",
+        "parameters": [
+          {"name": "processId", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["inputs"],
+                "properties": {"inputs": {"type": "object"}}
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {"description": "job", "content": {"application/json": {}}}
+        }
+      },
+      "delete": {
+        "operationId": "dismiss",
+        "parameters": [
+          {"name": "processId", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {"204": {"description": "dismissed"}}
+      }
+    }
+  }
+}`
+
+func TestCallWithBody(t *testing.T) {
+	var method, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, contentType, body = r.Method, r.Header.Get("Content-Type"), string(b)
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer srv.Close()
+	spec, err := parseSpec([]byte(bodySpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := testService(srv, spec)
+
+	op, err := svc.GetOperation("execute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Method != "POST" || op.Body == nil || !op.Body.Required || strings.Join(op.Body.ContentTypes, ",") != "application/json" {
+		t.Fatalf("operation %+v %+v", op, op.Body)
+	}
+	call, err := op.Call(map[string]interface{}{"processId": "buffer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := call.buildRequest(); err == nil {
+		t.Error("expected error for missing required body")
+	}
+	if _, err := call.WithBody("", []byte(`{"outputs": {}}`)); err == nil {
+		t.Error("expected schema validation error")
+	}
+	if _, err := call.WithBody("text/plain", []byte(`x`)); err == nil {
+		t.Error("expected error for undeclared content type")
+	}
+	call, err = call.WithBody("json", []byte(`{"inputs": {"distance": 10}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := call.ExecuteWriter(&out); err != nil {
+		t.Fatal(err)
+	}
+	if method != "POST" || contentType != "application/json" || body != `{"inputs": {"distance": 10}}` || out.String() != `{"id": "1"}` {
+		t.Errorf("post %s %s %s -> %s", method, contentType, body, out.String())
+	}
+
+	op, err = svc.GetOperation("dismiss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, _ = op.Call(map[string]interface{}{"processId": "buffer"})
+	if _, err := call.WithBody("json", []byte(`{}`)); err == nil {
+		t.Error("expected error for operation without body")
+	}
+	out.Reset()
+	if err := call.ExecuteWriter(&out); err != nil || method != "DELETE" || out.Len() != 0 {
+		t.Errorf("delete %s %q %v", method, out.String(), err)
+	}
+}
@@ -0,0 +1,244 @@
+package wfs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Collection represents a single feature collection offered by a Service.
+type Collection struct {
+	svc  Service
+	Name string
+}
+
+// Collection returns the Collection with the given name. No request is made
+// so the existence of the collection is not verified.
+func (s Service) Collection(name string) Collection {
+	return Collection{s, name}
+}
+
+// Property describes a feature property as published by the collection
+// schema or queryables. Type is the JSON schema type.
+type Property struct {
+	Name   string
+	Type   string
+	Format string
+}
+
+type schemaDoc struct {
+	Properties map[string]struct {
+		Type   interface{} `json:"type"`
+		Format string      `json:"format"`
+		Role   string      `json:"x-ogc-role"`
+	} `json:"properties"`
+	Queryables []struct {
+		Queryable string `json:"queryable"`
+		Type      string `json:"type"`
+	} `json:"queryables"`
+}
+
+// properties returns the non-geometry properties sorted by name.
+func (d schemaDoc) properties() []Property {
+	props := []Property{}
+	for k, v := range d.Properties {
+		if v.Role == "primary-geometry" || strings.HasPrefix(v.Format, "geometry-") {
+			continue
+		}
+		p := Property{Name: k, Format: v.Format}
+		switch t := v.Type.(type) {
+		case string:
+			p.Type = t
+		case []interface{}:
+			// nullable types are given as ["string", "null"]
+			for _, e := range t {
+				if s, ok := e.(string); ok && s != "null" {
+					p.Type = s
+				}
+			}
+		}
+		props = append(props, p)
+	}
+	for _, q := range d.Queryables {
+		props = append(props, Property{Name: q.Queryable, Type: q.Type})
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+// Queryables returns the names of the properties that may be used in queries
+// against the collection. Both the JSON schema form and the older list form
+// of the queryables document are understood.
+func (c Collection) Queryables(ctx context.Context) ([]string, error) {
+	u, err := c.svc.paths.queryables(c.Name)
+	if err != nil {
+		return nil, err
+	}
+	var doc schemaDoc
+	if _, err := c.svc.cl.getJSON(ctx, u, nil, "json", &doc); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, p := range doc.properties() {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// Schema returns the feature properties of the collection. The schema
+// document is used if the server publishes one, otherwise the queryables.
+func (c Collection) Schema(ctx context.Context) ([]Property, error) {
+	u, err := c.svc.paths.schema(c.Name)
+	if err != nil {
+		return nil, err
+	}
+	var doc schemaDoc
+	_, err = c.svc.cl.getJSON(ctx, u, nil, "json", &doc)
+	if isNotFound(err) {
+		if u, err = c.svc.paths.queryables(c.Name); err == nil {
+			_, err = c.svc.cl.getJSON(ctx, u, nil, "json", &doc)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.properties(), nil
+}
+
+// Items requests a page of features from the collection. Properties and
+// sortby keys are validated against the collection queryables when the
+// server publishes them, and Crs and BBoxCrs against the CRS the collection
+// lists. Options the server does not declare conformance to are applied to
+// the returned page locally.
+//
+// Geometries are returned in x, y order: coordinates served in a CRS with
+// latitude or northing first axis order are swapped while decoding.
+func (c Collection) Items(ctx context.Context, q ItemsQuery) (FeatureCollection, error) {
+	values, local, err := c.prepare(ctx, q)
+	if err != nil {
+		return FeatureCollection{}, err
+	}
+	u, err := c.svc.paths.collectionItems(c.Name)
+	if err != nil {
+		return FeatureCollection{}, err
+	}
+	return c.page(ctx, u, values, q, local)
+}
+
+// prepare validates q and splits it into the request parameters and the
+// options to apply locally.
+func (c Collection) prepare(ctx context.Context, q ItemsQuery) (url.Values, ItemsQuery, error) {
+	if len(q.Properties) > 0 || len(q.SortBy) > 0 {
+		queryables, err := c.Queryables(ctx)
+		if err != nil && !isNotFound(err) {
+			return nil, q, err
+		}
+		if err == nil {
+			if err := q.Validate(queryables); err != nil {
+				return nil, q, err
+			}
+		}
+	}
+	if q.Crs != "" || q.BBoxCrs != "" {
+		info, err := c.Info(ctx)
+		if err != nil && !isNotFound(err) {
+			return nil, q, err
+		}
+		if err == nil {
+			if err := q.validateCrs(info); err != nil {
+				return nil, q, err
+			}
+		}
+	}
+	conf, err := c.svc.Conformance(ctx)
+	if err != nil && !isNotFound(err) {
+		return nil, q, err
+	}
+	values, local := q.values(conf)
+	return values, local, nil
+}
+
+// featureMediaTypes are accepted for feature collections unless the query
+// asks for a specific format.
+var featureMediaTypes = []MediaType{
+	MediaTypes.LookupShort("geojson"),
+	MediaTypes.LookupShort("json"),
+	MediaTypes.LookupShort("gml"),
+}
+
+// page requests and decodes a single page from u.
+func (c Collection) page(ctx context.Context, u string, values url.Values, q, local ItemsQuery) (FeatureCollection, error) {
+	accept := featureMediaTypes
+	if q.Format != "" {
+		accept = []MediaType{MediaTypes.Lookup(q.Format)}
+		if accept[0].Full == "" {
+			return FeatureCollection{}, fmt.Errorf("No Media Type: %s", q.Format)
+		}
+	}
+	body, header, err := c.svc.cl.get(ctx, u, values, accept)
+	if err != nil {
+		return FeatureCollection{}, err
+	}
+	return decodePage(u, body, header, q, local)
+}
+
+// decodePage decodes the feature collection response to u, bringing
+// geometries in x, y order and applying the local query options.
+func decodePage(u string, body []byte, header http.Header, q, local ItemsQuery) (FeatureCollection, error) {
+	var fc FeatureCollection
+	if err := decode(body, header.Get("Content-Type"), &fc); err != nil {
+		return FeatureCollection{}, fmt.Errorf("error decoding %s : %s", u, err)
+	}
+	if isXML(header.Get("Content-Type")) {
+		// GML geometries carry their own srsName and are already in x, y order
+		local.apply(&fc)
+		return fc, nil
+	}
+	fc.Crs = parseContentCrs(header.Get("Content-Crs"))
+	if fc.Crs == "" {
+		fc.Crs = CRSURI(q.Crs)
+	}
+	if fc.Crs == "" {
+		fc.Crs = CRS84
+	}
+	if LatitudeFirst(fc.Crs) {
+		for _, f := range fc.Features {
+			f.Geometry.Transform(swapAxes)
+		}
+	}
+	local.apply(&fc)
+	return fc, nil
+}
+
+// Info requests the metadata of the collection.
+func (c Collection) Info(ctx context.Context) (CollectionInfo, error) {
+	infos, err := c.svc.Collections(ctx)
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+	for _, info := range infos {
+		if info.Name == c.Name {
+			return info, nil
+		}
+	}
+	return CollectionInfo{}, fmt.Errorf("no collection %q", c.Name)
+}
+
+// Collections requests the metadata of all collections offered by the
+// Service.
+func (s Service) Collections(ctx context.Context) ([]CollectionInfo, error) {
+	var doc struct {
+		Collections []CollectionInfo `json:"collections"`
+	}
+	u, err := s.paths.collectionInfo()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.cl.getJSON(ctx, u, nil, "json", &doc); err != nil {
+		return nil, err
+	}
+	return doc.Collections, nil
+}
@@ -0,0 +1,46 @@
+package wfs
+
+import (
+	"context"
+	"strings"
+)
+
+// Conformance classes the client knows how to make use of. Servers are
+// matched on the trailing "/conf/<class>" segment as the part numbers and
+// versions of the drafts that define them keep moving.
+const (
+	ConformanceCore              = "core"
+	ConformanceGeoJSON           = "geojson"
+	ConformancePropertySelection = "property-selection"
+	ConformanceSorting           = "sorting"
+	ConformanceSkipGeometry      = "skip-geometry"
+)
+
+// Conformance is the set of conformance class URIs declared by a Service.
+type Conformance []string
+
+// ConformsTo reports whether the given class is declared, either by full
+// URI or by its trailing class name.
+func (c Conformance) ConformsTo(class string) bool {
+	for _, uri := range c {
+		if uri == class || strings.HasSuffix(uri, "/conf/"+class) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conformance requests the conformance declaration of the Service.
+func (s Service) Conformance(ctx context.Context) (Conformance, error) {
+	var doc struct {
+		ConformsTo []string `json:"conformsTo"`
+	}
+	u, err := s.paths.conformance()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.cl.getJSON(ctx, u, nil, "json", &doc); err != nil {
+		return nil, err
+	}
+	return Conformance(doc.ConformsTo), nil
+}
@@ -0,0 +1,82 @@
+package wfs
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ischneider/go-wfs-client/proj"
+)
+
+// CRS84 is the default coordinate reference system of WFS3: WGS 84 with
+// longitude, latitude axis order.
+const CRS84 = "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
+
+const epsgPrefix = "http://www.opengis.net/def/crs/EPSG/0/"
+
+// CRSURI returns the URI form of a CRS identifier. Short forms like
+// "EPSG:3857" and "CRS84" are expanded, anything else is returned as is.
+func CRSURI(id string) string {
+	switch {
+	case strings.EqualFold(id, "CRS84"), strings.EqualFold(id, "OGC:CRS84"):
+		return CRS84
+	case strings.HasPrefix(strings.ToUpper(id), "EPSG:"):
+		return epsgPrefix + id[len("EPSG:"):]
+	}
+	return id
+}
+
+// EPSGCode returns the EPSG code of a CRS identifier in either URI or short
+// form. CRS84 is reported as 4326.
+func EPSGCode(id string) (int, bool) {
+	uri := CRSURI(id)
+	if uri == CRS84 {
+		return 4326, true
+	}
+	if !strings.HasPrefix(uri, epsgPrefix) {
+		return 0, false
+	}
+	code, err := strconv.Atoi(uri[len(epsgPrefix):])
+	return code, err == nil
+}
+
+// latitudeFirst lists geographic EPSG CRS with latitude, longitude axis
+// order that are not in the proj registry.
+var latitudeFirst = map[int]bool{
+	4617: true, // NAD83(CSRS)
+	4674: true, // SIRGAS 2000
+	4612: true, // JGD2000
+	6668: true, // JGD2011
+	4490: true, // CGCS2000
+}
+
+// LatitudeFirst reports whether coordinates in the given CRS are expressed
+// with latitude before longitude, or northing before easting, as given by
+// the proj registry. CRS84 is always longitude first and unknown CRS are
+// assumed to be easting first.
+func LatitudeFirst(id string) bool {
+	uri := CRSURI(id)
+	if uri == CRS84 {
+		return false
+	}
+	code, ok := EPSGCode(uri)
+	if !ok {
+		return false
+	}
+	if c, err := proj.Lookup(code); err == nil {
+		return c.NorthingFirst()
+	}
+	return latitudeFirst[code]
+}
+
+// parseContentCrs extracts the CRS URI from a Content-Crs header value, which
+// is enclosed in angle brackets.
+func parseContentCrs(v string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(v), "<"), ">")
+}
+
+func swapAxes(p Position) Position {
+	if len(p) >= 2 {
+		p[0], p[1] = p[1], p[0]
+	}
+	return p
+}
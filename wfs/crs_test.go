@@ -0,0 +1,104 @@
+package wfs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCRS(t *testing.T) {
+	if u := CRSURI("EPSG:4326"); u != "http://www.opengis.net/def/crs/EPSG/0/4326" {
+		t.Errorf("uri %s", u)
+	}
+	if c, ok := EPSGCode("http://www.opengis.net/def/crs/EPSG/0/3857"); !ok || c != 3857 {
+		t.Errorf("code %d", c)
+	}
+	if LatitudeFirst("CRS84") || !LatitudeFirst("EPSG:4326") || LatitudeFirst("EPSG:3857") {
+		t.Errorf("axis order")
+	}
+	if !LatitudeFirst("EPSG:2180") || !LatitudeFirst(epsgPrefix+"31467") || LatitudeFirst("EPSG:25832") || !LatitudeFirst("EPSG:4490") {
+		t.Errorf("projected axis order")
+	}
+	if c := parseContentCrs("<http://www.opengis.net/def/crs/EPSG/0/4326>"); c != "http://www.opengis.net/def/crs/EPSG/0/4326" {
+		t.Errorf("content crs %s", c)
+	}
+}
+
+func TestBBoxCrsAxisOrder(t *testing.T) {
+	b := BBox{1, 2, 3, 4}
+	v, _ := ItemsQuery{BBox: &b, BBoxCrs: "EPSG:4326"}.values(nil)
+	if s := v.Get("bbox"); s != "2,1,4,3" {
+		t.Errorf("bbox %s", s)
+	}
+}
+
+func TestItemsCrs(t *testing.T) {
+	requested := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/collections/":
+			w.Write([]byte(`{"collections": [{"name": "lakes", "crs": ["http://www.opengis.net/def/crs/EPSG/0/3857"]},
+				{"id": "rivers", "crs": ["http://www.opengis.net/def/crs/EPSG/0/2180"]}]}`))
+		case "/collections/lakes/items/", "/collections/rivers/items/":
+			requested = r.URL.Query().Get("crs")
+			w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	coll := testService(srv, nil).Collection("lakes")
+	ctx := context.Background()
+	if _, err := coll.Items(ctx, ItemsQuery{Crs: "EPSG:3857"}); err != nil || requested != epsgPrefix+"3857" {
+		t.Errorf("requested %q %v", requested, err)
+	}
+	requested = ""
+	if _, err := coll.Items(ctx, ItemsQuery{Crs: "EPSG:2180"}); err == nil || requested != "" {
+		t.Errorf("expected error for unsupported crs, requested %q", requested)
+	}
+	if _, err := coll.Items(ctx, ItemsQuery{BBox: &BBox{0, 0, 1, 1}, BBoxCrs: "CRS84"}); err != nil {
+		t.Error(err)
+	}
+	coll.Name = "rivers"
+	if _, err := coll.Items(ctx, ItemsQuery{Crs: "EPSG:2180"}); err != nil || requested != epsgPrefix+"2180" {
+		t.Errorf("requested %q %v", requested, err)
+	}
+}
+
+func TestExtent(t *testing.T) {
+	for _, doc := range []string{
+		`[1,2,3,4]`,
+		`{"bbox":[1,2,3,4]}`,
+		`{"spatial":{"bbox":[[1,2,3,4]],"crs":"x"}}`,
+	} {
+		var e Extent
+		if err := json.Unmarshal([]byte(doc), &e); err != nil {
+			t.Errorf("%s : %v", doc, err)
+		}
+		if e.BBox != (BBox{1, 2, 3, 4}) {
+			t.Errorf("%s : %v", doc, e.BBox)
+		}
+	}
+}
+
+func TestReproject(t *testing.T) {
+	fc := FeatureCollection{Features: []Feature{{Geometry: &Geometry{Type: "Point", Point: Position{180, 0}}}}}
+	if err := fc.Reproject("EPSG:3857"); err != nil {
+		t.Fatal(err)
+	}
+	if p := fc.Features[0].Geometry.Point; p[0] < 20037508 || p[0] > 20037509 {
+		t.Errorf("point %v", p)
+	}
+	if fc.Crs != "http://www.opengis.net/def/crs/EPSG/0/3857" {
+		t.Errorf("crs %s", fc.Crs)
+	}
+	b, err := BBox{-1, -1, 1, 1}.Reproject(CRS84, "EPSG:3857")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] > -111319 || b[2] < 111319 {
+		t.Errorf("bbox %v", b)
+	}
+}
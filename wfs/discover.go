@@ -0,0 +1,113 @@
+package wfs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jban332/kin-openapi/openapi3"
+)
+
+// specAccept prefers the OpenAPI media types over generic JSON and YAML.
+const specAccept = "application/vnd.oai.openapi+json;version=3.0, application/vnd.oai.openapi+json;q=0.9, " +
+	"application/openapi+json;q=0.9, application/json;q=0.8, application/vnd.oai.openapi;q=0.7, " +
+	"application/yaml;q=0.6, application/x-yaml;q=0.6, text/yaml;q=0.6"
+
+// specCandidates are the locations tried, relative to the service root, when
+// the landing page has no service-desc link or it does not lead to a spec.
+var specCandidates = []string{"api.json", "openapi", "openapi.json", "openapi.yaml", "api.yaml"}
+
+// discoverSpec locates and parses the OpenAPI document of the service. The
+// service-desc links of the landing page are followed first, then the api
+// path of the path style and the usual file names. It returns the spec and
+// the URL it was read from.
+func (c Client) discoverSpec(ctx context.Context, paths pather) (*openapi3.Swagger, string, PatchReport, error) {
+	var candidates []string
+	seen := map[string]bool{}
+	add := func(u string) {
+		if !seen[u] {
+			seen[u] = true
+			candidates = append(candidates, u)
+		}
+	}
+	for _, u := range c.serviceDescLinks(ctx, paths.root) {
+		add(u)
+	}
+	if u, err := paths.spec(); err == nil {
+		add(u)
+	}
+	add(paths.root.ResolveReference(&url.URL{Path: "api"}).String())
+	for _, name := range specCandidates {
+		add(paths.root.ResolveReference(&url.URL{Path: name}).String())
+	}
+	var errs []string
+	for _, u := range candidates {
+		spec, report, err := c.fetchSpec(ctx, u)
+		if err == nil {
+			return spec, u, report, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, "", PatchReport{}, fmt.Errorf("no OpenAPI document found for %s :\n\t%s", paths.root, strings.Join(errs, "\n\t"))
+}
+
+// serviceDescLinks returns the service-desc links of the landing page at
+// root, OpenAPI types first. Failing to read the landing page is not an
+// error as older services do not have one.
+func (c Client) serviceDescLinks(ctx context.Context, root *url.URL) []string {
+	var landing struct {
+		Links []Link `json:"links"`
+	}
+	if _, err := c.getJSON(ctx, root.String(), nil, "json", &landing); err != nil {
+		return nil
+	}
+	var preferred, others []string
+	for _, l := range landing.Links {
+		if l.Rel != "service-desc" || l.Href == "" {
+			continue
+		}
+		ref, err := url.Parse(l.Href)
+		if err != nil {
+			continue
+		}
+		u := root.ResolveReference(ref).String()
+		if strings.Contains(l.Type, "openapi") {
+			preferred = append(preferred, u)
+		} else {
+			others = append(others, u)
+		}
+	}
+	return append(preferred, others...)
+}
+
+// fetchSpec requests and parses the OpenAPI document at u. Patched
+// components are logged.
+func (c Client) fetchSpec(ctx context.Context, u string) (*openapi3.Swagger, PatchReport, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, PatchReport{}, fmt.Errorf("invalid spec path: %s", u)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", specAccept)
+	data, header, err := c.fetch(req)
+	if err != nil {
+		return nil, PatchReport{}, err
+	}
+	if mediaBase(header.Get("Content-Type")) == "text/html" {
+		return nil, PatchReport{}, fmt.Errorf("%s is an HTML page", u)
+	}
+	spec, report, err := c.componentPatch().parseSpec(data)
+	if se, ok := err.(*SpecError); ok {
+		se.URL = u
+		return nil, report, se
+	}
+	if err != nil {
+		return nil, report, err
+	}
+	if len(report.Patched) > 0 {
+		c.logger().Printf("spec %s references undefined components, patched %s", u, strings.Join(report.Patched, ", "))
+	}
+	return spec, report, nil
+}
@@ -0,0 +1,202 @@
+package wfs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const yamlSpec = `openapi: 3.1.0
+info:
+  title: test
+  version: "1"
+paths:
+  /collections/{collectionId}/items:
+    get:
+      operationId: getFeatures
+      parameters:
+        - name: collectionId
+          in: path
+          required: true
+          schema:
+            type: string
+        - name: limit
+          in: query
+          schema:
+            type: [integer, "null"]
+            exclusiveMinimum: 0
+      responses:
+        "200":
+          description: features
+`
+
+func TestConnectDiscovery(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"links": [
+				{"rel": "service-doc", "href": "/docs", "type": "text/html"},
+				{"rel": "service-desc", "href": "/spec?f=yaml", "type": "application/vnd.oai.openapi;version=3.0"}]}`))
+		case "/spec":
+			w.Header().Set("Content-Type", "application/vnd.oai.openapi;version=3.0")
+			w.Write([]byte(yamlSpec))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := svc.Info().SpecURL; u != srv.URL+"/spec?f=yaml" {
+		t.Errorf("spec url %s", u)
+	}
+	if strings.Join(requested, ",") != "/,/spec" {
+		t.Errorf("requested %v", requested)
+	}
+	op, err := svc.GetOperation("getFeatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, _ := findParameter(op.Params, "limit")
+	if limit.Type != "integer" || !limit.p.Schema.Value.Nullable || !limit.p.Schema.Value.ExclusiveMin {
+		t.Errorf("limit schema %+v", limit.p.Schema.Value)
+	}
+}
+
+func TestConnectFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html>docs</html>`))
+		case "/openapi.json":
+			w.Write([]byte(`{"openapi": "3.0.1", "info": {"title": "test", "version": "1"}, "paths": {}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	svc, err := NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := svc.Info().SpecURL; u != srv.URL+"/openapi.json" {
+		t.Errorf("spec url %s", u)
+	}
+
+	srv.Config.Handler = http.NotFoundHandler()
+	if _, err := NewClient(srv.Client()).Connect(srv.URL, false); err == nil || !strings.Contains(err.Error(), "no OpenAPI document") {
+		t.Errorf("expected discovery error, got %v", err)
+	}
+}
+
+func TestSpecJSON(t *testing.T) {
+	for _, doc := range []string{`{"swagger": "2.0"}`, `<html></html>`, `{"openapi": "2.0"}`} {
+		if _, err := specJSON([]byte(doc)); err == nil {
+			t.Errorf("expected error for %s", doc)
+		}
+	}
+	b, err := specJSON([]byte(`{"openapi": "3.1.0", "components": {"schemas": {"a": {"const": "x"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"enum":["x"]`) || !strings.Contains(string(b), `"paths":{}`) {
+		t.Errorf("downgraded %s", b)
+	}
+}
+
+func TestDowngradeSpec(t *testing.T) {
+	b, err := specJSON([]byte(`{"openapi": "3.1.0",
+		"components": {"schemas": {"a": {"type": "object",
+			"properties": {"type": {"type": ["string", "null"]}},
+			"example": {"type": ["x", "y"], "const": 1}}}},
+		"paths": {"/p": {"get": {
+			"parameters": [{"name": "n", "in": "query", "schema": {"exclusiveMinimum": 0}}],
+			"responses": {"200": {"description": "ok", "content": {"application/json": {
+				"schema": {"type": "array", "items": {"allOf": [{"const": 2}]}},
+				"examples": {"e": {"value": {"const": 3}}}}}}}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"type":{"nullable":true,"type":"string"}`,
+		`"example":{"const":1,"type":["x","y"]}`,
+		`"schema":{"exclusiveMinimum":true,"minimum":0}`,
+		`"allOf":[{"enum":[2]}]`,
+		`"value":{"const":3}`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("missing %s in %s", want, b)
+		}
+	}
+}
+
+func TestConnectSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api.yaml")
+	if err := ioutil.WriteFile(path, []byte(yamlSpec+"servers:\n  - url: http://example.com/wfs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := LoadSpec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := NewClient(http.DefaultClient).ConnectSpec("", false, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := svc.Info(); info.URL != "http://example.com/wfs" || info.SpecURL != path {
+		t.Errorf("info %+v", info)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type": "Feature", "id": "a", "geometry": null, "properties": {}}`))
+	}))
+	defer srv.Close()
+	spec, err = ReadSpec(strings.NewReader(yamlSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(srv.Client()).ConnectSpec("", false, spec); err == nil {
+		t.Error("expected error without servers or URL")
+	}
+	svc, err = NewClient(srv.Client()).ConnectSpec(srv.URL+"/", false, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, _ := svc.GetOperation("getFeatures")
+	if op.URL() != srv.URL+"/collections/{collectionId}/items" {
+		t.Errorf("operation url %s", op.URL())
+	}
+	if f, err := svc.Collection("c").Item(context.Background(), "a"); err != nil || f.ID != "a" {
+		t.Errorf("item %+v %v", f, err)
+	}
+}
+
+func TestSpecNullEntry(t *testing.T) {
+	for doc, path := range map[string]string{
+		`{"openapi": "3.0.0", "paths": {"/a": {"get": {"parameters": [null]}}}}`:                           "paths./a.get.parameters.0",
+		`{"openapi": "3.0.0", "paths": {"/a": {"get": {"responses": {"200": null}}}}}`:                     "paths./a.get.responses.200",
+		`{"openapi": "3.0.0", "components": {"schemas": {"a": {"properties": {"b": null}}}}, "paths": {}}`: "components.schemas.a.properties.b",
+		`{"openapi": "3.0.0", "components": {"requestBodies": {"a": {"content": {"x": null}}}}}`:           "components.requestBodies.a.content.x",
+	} {
+		_, err := parseSpec([]byte(doc))
+		if se, ok := err.(*SpecError); !ok || se.Path != path {
+			t.Errorf("%s : %v", doc, err)
+		}
+	}
+}
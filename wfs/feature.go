@@ -0,0 +1,33 @@
+package wfs
+
+import "encoding/json"
+
+// Feature is a single GeoJSON feature as returned by a collection.
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	Links      []Link                 `json:"links,omitempty"`
+}
+
+// DecodeProperties decodes the properties of the feature into v, typically
+// a struct with JSON tags for the properties of its collection.
+func (f Feature) DecodeProperties(v interface{}) error {
+	b, err := json.Marshal(f.Properties)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// FeatureCollection is a single page of features. Crs is the coordinate
+// reference system of the geometries as reported by the Content-Crs header.
+type FeatureCollection struct {
+	Crs            string    `json:"-"`
+	Type           string    `json:"type"`
+	Features       []Feature `json:"features"`
+	Links          []Link    `json:"links,omitempty"`
+	NumberMatched  int       `json:"numberMatched,omitempty"`
+	NumberReturned int       `json:"numberReturned,omitempty"`
+}
@@ -0,0 +1,67 @@
+//go:build go1.18
+// +build go1.18
+
+package wfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func FuzzParseSpec(f *testing.F) {
+	for _, seed := range []string{negotiationSpec, bodySpec, yamlSpec, `{"openapi": "3.1.0"}`, `openapi: 3.0.0`, `{`, `[]`, `null`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var logged testLogger
+		cl := NewClient(http.DefaultClient).WithLogger(&logged)
+		spec, err := cl.ReadSpec(bytes.NewReader(data))
+		if err == nil && spec == nil {
+			t.Error("nil spec without error")
+		}
+		if err != nil {
+			if _, ok := err.(*SpecError); !ok {
+				t.Errorf("error of type %T", err)
+			}
+			return
+		}
+		// connecting must not panic on any document that parsed
+		for _, root := range []string{"", "http://server.domain/"} {
+			if svc, err := cl.ConnectSpec(root, false, spec); err == nil {
+				svc.Operations()
+			}
+		}
+	})
+}
+
+func FuzzPather(f *testing.F) {
+	f.Add("http://server.domain/path/", "newStyle", "c", "f")
+	f.Add("http://server.domain/", "oldStyle", "a:b", "a/b c?")
+	f.Add("", "", "%zz", "..")
+	f.Fuzz(func(t *testing.T, root, style, cid, fid string) {
+		u, err := url.Parse(root)
+		if err != nil {
+			return
+		}
+		p := pather{u, pathStyle(style)}
+		for _, build := range []func() (string, error){
+			p.collectionInfo,
+			p.spec,
+			p.conformance,
+			func() (string, error) { return p.collectionItems(cid) },
+			func() (string, error) { return p.collectionItem(cid, fid) },
+			func() (string, error) { return p.queryables(cid) },
+			func() (string, error) { return p.schema(cid) },
+		} {
+			got, err := build()
+			if err != nil {
+				continue
+			}
+			if g, err := url.Parse(got); err == nil && u.Host != "" && g.Host != u.Host {
+				t.Errorf("%q escapes the host of %q", got, root)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package wfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Position is a single coordinate tuple in x, y[, z] order.
+type Position []float64
+
+// Geometry is a GeoJSON geometry. Only the coordinate field matching Type is
+// populated.
+type Geometry struct {
+	Type            string
+	Point           Position
+	MultiPoint      []Position
+	LineString      []Position
+	MultiLineString [][]Position
+	Polygon         [][]Position
+	MultiPolygon    [][][]Position
+	Geometries      []*Geometry
+}
+
+type geometryJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []*Geometry     `json:"geometries,omitempty"`
+}
+
+// MarshalJSON encodes the Geometry as GeoJSON.
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	var coords interface{}
+	switch g.Type {
+	case "Point":
+		coords = g.Point
+	case "MultiPoint":
+		coords = g.MultiPoint
+	case "LineString":
+		coords = g.LineString
+	case "MultiLineString":
+		coords = g.MultiLineString
+	case "Polygon":
+		coords = g.Polygon
+	case "MultiPolygon":
+		coords = g.MultiPolygon
+	case "GeometryCollection":
+		return json.Marshal(struct {
+			Type       string      `json:"type"`
+			Geometries []*Geometry `json:"geometries"`
+		}{g.Type, g.Geometries})
+	default:
+		return nil, fmt.Errorf("unknown geometry type %q", g.Type)
+	}
+	return json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{g.Type, coords})
+}
+
+// UnmarshalJSON decodes a GeoJSON geometry.
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var raw geometryJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*g = Geometry{Type: raw.Type}
+	var dest interface{}
+	switch raw.Type {
+	case "Point":
+		dest = &g.Point
+	case "MultiPoint":
+		dest = &g.MultiPoint
+	case "LineString":
+		dest = &g.LineString
+	case "MultiLineString":
+		dest = &g.MultiLineString
+	case "Polygon":
+		dest = &g.Polygon
+	case "MultiPolygon":
+		dest = &g.MultiPolygon
+	case "GeometryCollection":
+		g.Geometries = raw.Geometries
+		return nil
+	default:
+		return fmt.Errorf("unknown geometry type %q", raw.Type)
+	}
+	return json.Unmarshal(raw.Coordinates, dest)
+}
+
+// Transform replaces every position of the Geometry with the result of fn.
+func (g *Geometry) Transform(fn func(Position) Position) {
+	if g == nil {
+		return
+	}
+	line := func(ps []Position) {
+		for i := range ps {
+			ps[i] = fn(ps[i])
+		}
+	}
+	if g.Point != nil {
+		g.Point = fn(g.Point)
+	}
+	line(g.MultiPoint)
+	line(g.LineString)
+	for _, l := range g.MultiLineString {
+		line(l)
+	}
+	for _, r := range g.Polygon {
+		line(r)
+	}
+	for _, p := range g.MultiPolygon {
+		for _, r := range p {
+			line(r)
+		}
+	}
+	for _, c := range g.Geometries {
+		c.Transform(fn)
+	}
+}
+
+// Bounds returns the bounding box of all positions of the Geometry. ok is
+// false if the Geometry has no positions.
+func (g *Geometry) Bounds() (b BBox, ok bool) {
+	g.Transform(func(p Position) Position {
+		if len(p) < 2 {
+			return p
+		}
+		if !ok {
+			b = BBox{p[0], p[1], p[0], p[1]}
+			ok = true
+			return p
+		}
+		b[0], b[1] = math.Min(b[0], p[0]), math.Min(b[1], p[1])
+		b[2], b[3] = math.Max(b[2], p[0]), math.Max(b[3], p[1])
+		return p
+	})
+	return b, ok
+}
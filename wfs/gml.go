@@ -0,0 +1,377 @@
+package wfs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// gmlNode is a generic XML element.
+type gmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []gmlNode  `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *gmlNode) attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *gmlNode) child(local string) *gmlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// isGML reports whether the element is in a GML namespace. GML 2 and 3.1
+// use the unversioned namespace.
+func (n *gmlNode) isGML() bool {
+	return strings.HasPrefix(n.XMLName.Space, "http://www.opengis.net/gml")
+}
+
+// DecodeGML decodes a wfs:FeatureCollection of GML 3.2 Simple Features into
+// a FeatureCollection. Members may be given as wfs:member, gml:featureMember
+// or gml:featureMembers. Property values are kept as strings as their types
+// are only known from the application schema.
+//
+// Coordinates are returned in x, y order: they are swapped when the srsName
+// of a geometry denotes a latitude first CRS in URN or URI form. The legacy
+// "EPSG:4326" form is taken as longitude first. The Crs of the collection is
+// the CRS of the first geometry, CRS84 if none is given.
+func DecodeGML(r io.Reader) (FeatureCollection, error) {
+	var root gmlNode
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return FeatureCollection{}, fmt.Errorf("error decoding GML : %s", err)
+	}
+	if root.XMLName.Local != "FeatureCollection" {
+		return FeatureCollection{}, fmt.Errorf("expected FeatureCollection, got %s", root.XMLName.Local)
+	}
+	d := &gmlDecoder{}
+	fc := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for i := range root.Nodes {
+		m := &root.Nodes[i]
+		switch m.XMLName.Local {
+		case "member", "featureMember", "featureMembers":
+		default:
+			continue
+		}
+		for j := range m.Nodes {
+			f, err := d.feature(&m.Nodes[j])
+			if err != nil {
+				return FeatureCollection{}, err
+			}
+			fc.Features = append(fc.Features, f)
+		}
+	}
+	if n, err := strconv.Atoi(root.attr("numberMatched")); err == nil {
+		fc.NumberMatched = n
+	}
+	fc.NumberReturned = len(fc.Features)
+	fc.Crs = d.crs
+	if fc.Crs == "" {
+		fc.Crs = CRS84
+	}
+	return fc, nil
+}
+
+type gmlDecoder struct {
+	crs string
+}
+
+func (d *gmlDecoder) feature(n *gmlNode) (Feature, error) {
+	f := Feature{Type: "Feature", Properties: map[string]interface{}{}}
+	if id := n.attr("id"); id != "" {
+		f.ID = id
+	}
+	for i := range n.Nodes {
+		p := &n.Nodes[i]
+		if p.isGML() && p.XMLName.Local == "boundedBy" {
+			continue
+		}
+		if len(p.Nodes) == 1 && p.Nodes[0].isGML() && gmlGeometries[p.Nodes[0].XMLName.Local] {
+			if f.Geometry != nil {
+				// only the first geometry property maps to the Feature
+				continue
+			}
+			g, err := d.geometry(&p.Nodes[0], gmlContext{})
+			if err != nil {
+				return Feature{}, fmt.Errorf("feature %s property %s : %s", n.attr("id"), p.XMLName.Local, err)
+			}
+			f.Geometry = g
+			continue
+		}
+		if p.attr("nil") == "true" {
+			f.Properties[p.XMLName.Local] = nil
+			continue
+		}
+		f.Properties[p.XMLName.Local] = strings.TrimSpace(p.innerText())
+	}
+	return f, nil
+}
+
+func (n *gmlNode) innerText() string {
+	if len(n.Nodes) == 0 {
+		return n.Text
+	}
+	var b strings.Builder
+	for i := range n.Nodes {
+		b.WriteString(n.Nodes[i].innerText())
+	}
+	return b.String()
+}
+
+var gmlGeometries = map[string]bool{
+	"Point":           true,
+	"LineString":      true,
+	"Polygon":         true,
+	"MultiPoint":      true,
+	"MultiLineString": true,
+	"MultiCurve":      true,
+	"MultiPolygon":    true,
+	"MultiSurface":    true,
+	"MultiGeometry":   true,
+}
+
+// gmlContext carries the inherited srsName and srsDimension attributes.
+type gmlContext struct {
+	latFirst bool
+	dim      int
+}
+
+func (d *gmlDecoder) inherit(n *gmlNode, ctx gmlContext) gmlContext {
+	if srs := n.attr("srsName"); srs != "" {
+		uri, latFirst := gmlCRS(srs)
+		if d.crs == "" {
+			d.crs = uri
+		}
+		ctx.latFirst = latFirst
+	}
+	if dim, err := strconv.Atoi(n.attr("srsDimension")); err == nil && dim > 0 {
+		ctx.dim = dim
+	}
+	return ctx
+}
+
+// gmlCRS returns the URI of a srsName and whether its coordinates are
+// latitude first.
+func gmlCRS(srs string) (string, bool) {
+	switch {
+	case strings.HasPrefix(strings.ToLower(srs), "urn:ogc:def:crs:"):
+		parts := strings.Split(srs, ":")
+		auth, code := strings.ToUpper(parts[4]), parts[len(parts)-1]
+		if auth == "OGC" && code == "CRS84" {
+			return CRS84, false
+		}
+		uri := CRSURI(auth + ":" + code)
+		return uri, LatitudeFirst(uri)
+	case strings.HasPrefix(strings.ToUpper(srs), "EPSG:"):
+		return CRSURI(srs), false
+	case strings.Contains(srs, "epsg.xml#"):
+		return CRSURI("EPSG:" + srs[strings.Index(srs, "#")+1:]), false
+	}
+	return srs, LatitudeFirst(srs)
+}
+
+func (d *gmlDecoder) geometry(n *gmlNode, ctx gmlContext) (*Geometry, error) {
+	ctx = d.inherit(n, ctx)
+	switch n.XMLName.Local {
+	case "Point":
+		ps, err := d.positions(n, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(ps) != 1 {
+			return nil, fmt.Errorf("Point with %d positions", len(ps))
+		}
+		return &Geometry{Type: "Point", Point: ps[0]}, nil
+	case "LineString", "LinearRing":
+		ps, err := d.positions(n, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Geometry{Type: "LineString", LineString: ps}, nil
+	case "Polygon":
+		var rings [][]Position
+		for i := range n.Nodes {
+			b := &n.Nodes[i]
+			switch b.XMLName.Local {
+			case "exterior", "interior", "outerBoundaryIs", "innerBoundaryIs":
+			default:
+				continue
+			}
+			ring := b.child("LinearRing")
+			if ring == nil {
+				return nil, fmt.Errorf("%s without LinearRing", b.XMLName.Local)
+			}
+			r, err := d.geometry(ring, ctx)
+			if err != nil {
+				return nil, err
+			}
+			rings = append(rings, r.LineString)
+		}
+		return &Geometry{Type: "Polygon", Polygon: rings}, nil
+	}
+	members, err := d.members(n, ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.XMLName.Local {
+	case "MultiPoint":
+		g := &Geometry{Type: "MultiPoint", MultiPoint: []Position{}}
+		for _, m := range members {
+			if m.Type != "Point" {
+				return nil, fmt.Errorf("%s in MultiPoint", m.Type)
+			}
+			g.MultiPoint = append(g.MultiPoint, m.Point)
+		}
+		return g, nil
+	case "MultiLineString", "MultiCurve":
+		g := &Geometry{Type: "MultiLineString", MultiLineString: [][]Position{}}
+		for _, m := range members {
+			if m.Type != "LineString" {
+				return nil, fmt.Errorf("%s in %s", m.Type, n.XMLName.Local)
+			}
+			g.MultiLineString = append(g.MultiLineString, m.LineString)
+		}
+		return g, nil
+	case "MultiPolygon", "MultiSurface":
+		g := &Geometry{Type: "MultiPolygon", MultiPolygon: [][][]Position{}}
+		for _, m := range members {
+			if m.Type != "Polygon" {
+				return nil, fmt.Errorf("%s in %s", m.Type, n.XMLName.Local)
+			}
+			g.MultiPolygon = append(g.MultiPolygon, m.Polygon)
+		}
+		return g, nil
+	case "MultiGeometry":
+		return &Geometry{Type: "GeometryCollection", Geometries: members}, nil
+	}
+	return nil, fmt.Errorf("unsupported GML geometry %s", n.XMLName.Local)
+}
+
+// members decodes the geometries of the member properties of a multi
+// geometry, such as pointMember or surfaceMembers.
+func (d *gmlDecoder) members(n *gmlNode, ctx gmlContext) ([]*Geometry, error) {
+	var gs []*Geometry
+	for i := range n.Nodes {
+		m := &n.Nodes[i]
+		if !strings.HasSuffix(m.XMLName.Local, "Member") && !strings.HasSuffix(m.XMLName.Local, "Members") {
+			continue
+		}
+		for j := range m.Nodes {
+			g, err := d.geometry(&m.Nodes[j], ctx)
+			if err != nil {
+				return nil, err
+			}
+			gs = append(gs, g)
+		}
+	}
+	return gs, nil
+}
+
+// positions decodes the pos, posList or GML 2 coordinates children of n.
+func (d *gmlDecoder) positions(n *gmlNode, ctx gmlContext) ([]Position, error) {
+	var ps []Position
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		cctx := d.inherit(c, ctx)
+		var (
+			more []Position
+			err  error
+		)
+		switch c.XMLName.Local {
+		case "pos":
+			more, err = parsePosList(c.Text, 0)
+		case "posList":
+			// unlike pos, a list needs a dimension to split its ordinates
+			dim := cctx.dim
+			if dim == 0 {
+				dim = 2
+			}
+			more, err = parsePosList(c.Text, dim)
+		case "coordinates":
+			more, err = parseCoordinates(c)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, more...)
+	}
+	if ctx.latFirst {
+		for _, p := range ps {
+			swapAxes(p)
+		}
+	}
+	return ps, nil
+}
+
+// parsePosList parses whitespace separated ordinates in tuples of dim, or a
+// single position of any dimension if dim is 0.
+func parsePosList(s string, dim int) ([]Position, error) {
+	fields := strings.Fields(s)
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ordinate %q", f)
+		}
+		values[i] = v
+	}
+	if dim == 0 {
+		if len(values) < 2 {
+			return nil, fmt.Errorf("invalid position %q", s)
+		}
+		return []Position{values}, nil
+	}
+	if len(values)%dim != 0 {
+		return nil, fmt.Errorf("%d ordinates do not form positions of dimension %d", len(values), dim)
+	}
+	ps := make([]Position, 0, len(values)/dim)
+	for i := 0; i < len(values); i += dim {
+		ps = append(ps, Position(values[i:i+dim:i+dim]))
+	}
+	return ps, nil
+}
+
+// parseCoordinates parses the GML 2 coordinates element with its cs and ts
+// separators.
+func parseCoordinates(n *gmlNode) ([]Position, error) {
+	cs, ts := n.attr("cs"), n.attr("ts")
+	if cs == "" {
+		cs = ","
+	}
+	var tuples []string
+	if ts == "" {
+		tuples = strings.Fields(n.Text)
+	} else {
+		tuples = strings.Split(strings.TrimSpace(n.Text), ts)
+	}
+	var ps []Position
+	for _, t := range tuples {
+		p, err := parsePosList(strings.Replace(t, cs, " ", -1), 0)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p[0])
+	}
+	return ps, nil
+}
+
+// isXML reports whether a Content-Type denotes an XML document.
+func isXML(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	return strings.HasSuffix(ct, "/xml") || strings.HasSuffix(ct, "+xml")
+}
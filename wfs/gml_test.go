@@ -0,0 +1,102 @@
+package wfs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const gmlDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<wfs:FeatureCollection xmlns:wfs="http://www.opengis.net/wfs/3.0"
+    xmlns:gml="http://www.opengis.net/gml/3.2"
+    xmlns:app="http://example.com/app"
+    xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" numberMatched="3">
+  <wfs:featureMember>
+    <app:city gml:id="city.1">
+      <gml:boundedBy><gml:Envelope><gml:lowerCorner>0 0</gml:lowerCorner></gml:Envelope></gml:boundedBy>
+      <app:name>Bern</app:name>
+      <app:population xsi:nil="true"/>
+      <app:location>
+        <gml:Point srsName="urn:ogc:def:crs:EPSG::4326"><gml:pos>46.95 7.45</gml:pos></gml:Point>
+      </app:location>
+    </app:city>
+  </wfs:featureMember>
+  <wfs:featureMember>
+    <app:lake gml:id="lake.1">
+      <app:shape>
+        <gml:MultiSurface srsName="http://www.opengis.net/def/crs/EPSG/0/3857">
+          <gml:surfaceMember>
+            <gml:Polygon>
+              <gml:exterior><gml:LinearRing><gml:posList srsDimension="2">0 0 4 0 4 3 0 0</gml:posList></gml:LinearRing></gml:exterior>
+              <gml:interior><gml:LinearRing><gml:posList>1 1 2 1 2 2 1 1</gml:posList></gml:LinearRing></gml:interior>
+            </gml:Polygon>
+          </gml:surfaceMember>
+        </gml:MultiSurface>
+      </app:shape>
+    </app:lake>
+  </wfs:featureMember>
+  <wfs:featureMember>
+    <app:road gml:id="road.1">
+      <app:line>
+        <gml:LineString srsName="EPSG:4326"><gml:coordinates>7,46 8,47</gml:coordinates></gml:LineString>
+      </app:line>
+    </app:road>
+  </wfs:featureMember>
+</wfs:FeatureCollection>`
+
+func TestDecodeGML(t *testing.T) {
+	fc, err := DecodeGML(strings.NewReader(gmlDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 3 || fc.NumberMatched != 3 {
+		t.Fatalf("features %d matched %d", len(fc.Features), fc.NumberMatched)
+	}
+	if fc.Crs != epsgPrefix+"4326" {
+		t.Errorf("crs %s", fc.Crs)
+	}
+	city := fc.Features[0]
+	if city.ID != "city.1" || city.Properties["name"] != "Bern" || city.Properties["population"] != nil {
+		t.Errorf("city %v %v", city.ID, city.Properties)
+	}
+	if _, ok := city.Properties["boundedBy"]; ok {
+		t.Error("boundedBy decoded as property")
+	}
+	if !reflect.DeepEqual(city.Geometry.Point, Position{7.45, 46.95}) {
+		t.Errorf("point %v", city.Geometry.Point)
+	}
+	lake := fc.Features[1].Geometry
+	if lake.Type != "MultiPolygon" || len(lake.MultiPolygon) != 1 || len(lake.MultiPolygon[0]) != 2 {
+		t.Fatalf("lake %+v", lake)
+	}
+	if !reflect.DeepEqual(lake.MultiPolygon[0][0][2], Position{4, 3}) {
+		t.Errorf("ring %v", lake.MultiPolygon[0][0])
+	}
+	if !reflect.DeepEqual(lake.MultiPolygon[0][1], []Position{{1, 1}, {2, 1}, {2, 2}, {1, 1}}) {
+		t.Errorf("interior ring %v", lake.MultiPolygon[0][1])
+	}
+	road := fc.Features[2].Geometry
+	if !reflect.DeepEqual(road.LineString, []Position{{7, 46}, {8, 47}}) {
+		t.Errorf("road %v", road.LineString)
+	}
+}
+
+func TestGMLCRS(t *testing.T) {
+	for _, tc := range []struct {
+		srs      string
+		uri      string
+		latFirst bool
+	}{
+		{"urn:ogc:def:crs:EPSG::4326", epsgPrefix + "4326", true},
+		{"urn:ogc:def:crs:OGC:1.3:CRS84", CRS84, false},
+		{"EPSG:4326", epsgPrefix + "4326", false},
+		{"http://www.opengis.net/gml/srs/epsg.xml#4326", epsgPrefix + "4326", false},
+		{epsgPrefix + "4258", epsgPrefix + "4258", true},
+		{"urn:ogc:def:crs:EPSG::25832", epsgPrefix + "25832", false},
+	} {
+		uri, latFirst := gmlCRS(tc.srs)
+		if uri != tc.uri || latFirst != tc.latFirst {
+			t.Errorf("%s: got %s %v", tc.srs, uri, latFirst)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package wfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Item requests a single feature of the collection by its ID. The Links of
+// the Feature are those of the response. As for Items, coordinates served in
+// a latitude first CRS are swapped to x, y order.
+func (c Collection) Item(ctx context.Context, fid string) (Feature, error) {
+	f, _, err := c.ItemForUpdate(ctx, fid)
+	return f, err
+}
+
+// ItemForUpdate works as per Item but also returns the ETag of the feature,
+// if the server sends one, to be passed to Replace, Update or Delete.
+func (c Collection) ItemForUpdate(ctx context.Context, fid string) (Feature, string, error) {
+	u, err := c.svc.paths.collectionItem(c.Name, fid)
+	if err != nil {
+		return Feature{}, "", err
+	}
+	body, header, err := c.svc.cl.get(ctx, u, nil, featureMediaTypes[:2])
+	if err != nil {
+		return Feature{}, "", err
+	}
+	var f Feature
+	if err := decodeJSON(body, &f); err != nil {
+		return Feature{}, "", fmt.Errorf("error decoding %s : %s", u, err)
+	}
+	if LatitudeFirst(parseContentCrs(header.Get("Content-Crs"))) {
+		f.Geometry.Transform(swapAxes)
+	}
+	return f, header.Get("ETag"), nil
+}
+
+// ItemResult is the outcome of fetching one feature with ItemsByID.
+type ItemResult struct {
+	ID      string
+	Feature Feature
+	ETag    string
+	Err     error
+}
+
+// ItemsByID fetches the features with the given IDs concurrently using at
+// most workers requests at a time. The results are in the order of ids, each
+// with its own error, so a missing feature does not fail the others.
+func (c Collection) ItemsByID(ctx context.Context, workers int, ids ...string) []ItemResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]ItemResult, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(ids); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f, etag, err := c.ItemForUpdate(ctx, ids[i])
+				results[i] = ItemResult{ids[i], f, etag, err}
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,49 @@
+package wfs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestItemsByID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		prefix := "/collections/lakes/items/"
+		if !strings.HasPrefix(path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		id, _ := url.PathUnescape(strings.TrimSuffix(path[len(prefix):], "/"))
+		if id == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		fmt.Fprintf(w, `{"type": "Feature", "id": %q, "geometry": {"type": "Point", "coordinates": [1, 2]},
+			"properties": {}, "links": [{"href": "self", "rel": "self"}]}`, id)
+	}))
+	defer srv.Close()
+	coll := testService(srv, nil).Collection("lakes")
+
+	ids := []string{"a/b", "with space", "missing", "plain"}
+	results := coll.ItemsByID(context.Background(), 2, ids...)
+	for i, r := range results {
+		if r.ID != ids[i] {
+			t.Errorf("result %d for %s", i, r.ID)
+		}
+		if r.ID == "missing" {
+			if !isNotFound(r.Err) {
+				t.Errorf("expected not found, got %v", r.Err)
+			}
+			continue
+		}
+		if r.Err != nil || r.Feature.ID != r.ID || len(r.Feature.Links) != 1 {
+			t.Errorf("%s: %+v %v", r.ID, r.Feature, r.Err)
+		}
+	}
+}
@@ -1,12 +1,105 @@
 package wfs
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // CollectionInfo is a partial model of the WFS3 concept.
 type CollectionInfo struct {
-	Name        string `json:"name"`
-	Title       string `json:"name"`
-	Description string `json:"name"`
-	Extent      BBox   `json:"extent"`
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Extent      Extent   `json:"extent"`
+	Links       []Link   `json:"links"`
+	Crs         []string `json:"crs,omitempty"`
+	StorageCrs  string   `json:"storageCrs,omitempty"`
+}
+
+// UnmarshalJSON takes the Name from the id of OGC API Features collections,
+// falling back to the name of the WFS 3 drafts.
+func (c *CollectionInfo) UnmarshalJSON(data []byte) error {
+	type plain CollectionInfo
+	var doc struct {
+		plain
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*c = CollectionInfo(doc.plain)
+	if doc.ID != "" {
+		c.Name = doc.ID
+	}
+	return nil
 }
 
 // BBox describes an extent in the form of lx, ly, ux, uy.
 type BBox [4]float64
+
+// ParseBBox parses a bbox given as comma separated lx,ly,ux,uy.
+func ParseBBox(s string) (BBox, error) {
+	var b BBox
+	parts := strings.Split(s, ",")
+	if len(parts) != len(b) {
+		return b, fmt.Errorf("bbox requires 4 values, got %q", s)
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return b, fmt.Errorf("invalid bbox value %q", p)
+		}
+		b[i] = v
+	}
+	return b, nil
+}
+
+// Extent is the spatial extent of a collection. Crs is empty for the default
+// of CRS84.
+type Extent struct {
+	BBox BBox   `json:"bbox"`
+	Crs  string `json:"crs,omitempty"`
+}
+
+// UnmarshalJSON accepts the bare bbox array, the older {bbox, crs} object
+// and the newer {spatial: {bbox: [[...]], crs}} object.
+func (e *Extent) UnmarshalJSON(data []byte) error {
+	var bbox BBox
+	if err := json.Unmarshal(data, &bbox); err == nil {
+		*e = Extent{BBox: bbox}
+		return nil
+	}
+	var doc struct {
+		BBox    *BBox  `json:"bbox"`
+		Crs     string `json:"crs"`
+		Spatial *struct {
+			BBox []BBox `json:"bbox"`
+			Crs  string `json:"crs"`
+		} `json:"spatial"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*e = Extent{Crs: doc.Crs}
+	if doc.BBox != nil {
+		e.BBox = *doc.BBox
+	}
+	if s := doc.Spatial; s != nil {
+		if len(s.BBox) > 0 {
+			e.BBox = s.BBox[0]
+		}
+		e.Crs = s.Crs
+	}
+	return nil
+}
+
+// Link is a hypermedia reference as used throughout WFS3 responses.
+type Link struct {
+	Href     string `json:"href"`
+	Rel      string `json:"rel,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Hreflang string `json:"hreflang,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
@@ -0,0 +1,133 @@
+package wfs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// operationIndex holds the operations of a spec sorted by path and method,
+// indexed by ID, by method and path and by tag.
+type operationIndex struct {
+	ops    []Operation
+	byID   map[string]int
+	byPath map[string]int
+	byTag  map[string][]int
+	// problems describes the operations whose ID was missing or duplicated
+	// and had to be synthesized
+	problems []string
+}
+
+// operations returns the index built when connecting, or builds one for
+// Services assembled otherwise.
+func (s Service) operations() *operationIndex {
+	if s.ops != nil {
+		return s.ops
+	}
+	return s.indexOperations()
+}
+
+func (s Service) indexOperations() *operationIndex {
+	idx := &operationIndex{
+		byID:   map[string]int{},
+		byPath: map[string]int{},
+		byTag:  map[string][]int{},
+	}
+	for path, item := range s.spec.Paths {
+		if item == nil {
+			continue
+		}
+		for method, op := range item.Operations() {
+			idx.ops = append(idx.ops, s.operationFromSwagger(path, method, op))
+		}
+	}
+	sort.Slice(idx.ops, func(i, j int) bool {
+		a, b := idx.ops[i], idx.ops[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	// declared IDs are claimed first so that synthesized ones cannot take
+	// them, the first operation in sorted order keeping a duplicated ID
+	var unnamed []int
+	for i, op := range idx.ops {
+		if _, dup := idx.byID[op.ID]; op.ID == "" || dup {
+			unnamed = append(unnamed, i)
+			continue
+		}
+		idx.byID[op.ID] = i
+	}
+	for _, i := range unnamed {
+		op := &idx.ops[i]
+		id := synthesizeID(op.Method, op.Path)
+		for n := 2; ; n++ {
+			if _, taken := idx.byID[id]; !taken {
+				break
+			}
+			id = fmt.Sprintf("%s%d", synthesizeID(op.Method, op.Path), n)
+		}
+		if op.ID == "" {
+			idx.problems = append(idx.problems, fmt.Sprintf("%s %s has no operationId, using %s", op.Method, op.Path, id))
+		} else {
+			idx.problems = append(idx.problems, fmt.Sprintf("%s %s repeats operationId %s, using %s", op.Method, op.Path, op.ID, id))
+		}
+		op.ID = id
+		idx.byID[id] = i
+	}
+	for i, op := range idx.ops {
+		idx.byPath[op.Method+" "+op.Path] = i
+		for _, t := range op.Tags {
+			idx.byTag[t] = append(idx.byTag[t], i)
+		}
+		if len(op.Tags) == 0 {
+			idx.byTag[""] = append(idx.byTag[""], i)
+		}
+	}
+	return idx
+}
+
+// synthesizeID derives an operation ID from the method and path, as in
+// "getCollectionsCollectionIdItems" for GET /collections/{collectionId}/items.
+func synthesizeID(method, path string) string {
+	id := []rune(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		id = append(id, r)
+	}
+	return string(id)
+}
+
+// OperationByPath returns the Operation for the method and the path as
+// declared in the spec, such as "/collections/{collectionId}/items".
+func (s Service) OperationByPath(method, path string) (Operation, error) {
+	idx := s.operations()
+	i, ok := idx.byPath[strings.ToUpper(method)+" "+path]
+	if !ok {
+		return Operation{}, fmt.Errorf("no operation %s %s", strings.ToUpper(method), path)
+	}
+	return idx.ops[i], nil
+}
+
+// OperationsByTag returns the Operations grouped by their OpenAPI tags, in
+// the order of Operations. An Operation with several tags is listed under
+// each; untagged ones are listed under "".
+func (s Service) OperationsByTag() map[string][]Operation {
+	idx := s.operations()
+	tags := map[string][]Operation{}
+	for t, is := range idx.byTag {
+		for _, i := range is {
+			tags[t] = append(tags[t], idx.ops[i])
+		}
+	}
+	return tags
+}
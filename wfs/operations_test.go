@@ -0,0 +1,89 @@
+package wfs
+
+import (
+	"strings"
+	"testing"
+)
+
+const operationsSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/collections/{collectionId}/items": {
+      "get": {"operationId": "getFeatures", "tags": ["Data"], "responses": {"200": {"description": "ok"}}},
+      "post": {"tags": ["Data", "Transactions"], "responses": {"201": {"description": "created"}}}
+    },
+    "/collections": {
+      "get": {"operationId": "getFeatures", "tags": ["Capabilities"], "responses": {"200": {"description": "ok"}}}
+    },
+    "/conformance": {
+      "get": {"operationId": "getConformance", "responses": {"200": {"description": "ok"}}}
+    },
+    "/api": {
+      "get": {"operationId": "getConformance2", "responses": {"200": {"description": "ok"}}},
+      "put": {"operationId": "getConformance", "responses": {"200": {"description": "ok"}}}
+    }
+  }
+}`
+
+func TestOperationIndex(t *testing.T) {
+	spec, err := parseSpec([]byte(operationsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged testLogger
+	svc := NewClient(nil).WithLogger(&logged).indexed(Service{spec: spec})
+
+	var order []string
+	for _, op := range svc.Operations() {
+		order = append(order, op.Method+" "+op.Path+" "+op.ID)
+	}
+	want := []string{
+		"GET /api getConformance2",
+		"PUT /api getConformance",
+		"GET /collections getFeatures",
+		"GET /collections/{collectionId}/items getCollectionsCollectionIdItems",
+		"POST /collections/{collectionId}/items postCollectionsCollectionIdItems",
+		"GET /conformance getConformance3",
+	}
+	if strings.Join(order, "\n") != strings.Join(want, "\n") {
+		t.Errorf("operations\n%s", strings.Join(order, "\n"))
+	}
+	// the first in order keeps a repeated ID and synthesized IDs avoid
+	// declared ones
+	if len(logged) != 3 || !strings.Contains(logged[0], "GET /collections/{collectionId}/items repeats operationId getFeatures") ||
+		!strings.Contains(logged[1], "POST /collections/{collectionId}/items has no operationId") ||
+		!strings.Contains(logged[2], "using getConformance3") {
+		t.Errorf("logged %q", logged)
+	}
+
+	for _, id := range []string{"getFeatures", "getConformance3", "postCollectionsCollectionIdItems"} {
+		if _, err := svc.GetOperation(id); err != nil {
+			t.Error(err)
+		}
+	}
+	op, err := svc.OperationByPath("post", "/collections/{collectionId}/items")
+	if err != nil || op.ID != "postCollectionsCollectionIdItems" {
+		t.Errorf("by path %+v %v", op, err)
+	}
+	if _, err := svc.OperationByPath("DELETE", "/collections"); err == nil {
+		t.Error("expected error for undeclared method")
+	}
+
+	tags := svc.OperationsByTag()
+	if len(tags["Data"]) != 2 || len(tags["Transactions"]) != 1 || len(tags["Capabilities"]) != 1 || len(tags[""]) != 3 {
+		t.Errorf("tags %v", tags)
+	}
+	if tags["Data"][0].Method != "GET" {
+		t.Errorf("tag order %v", tags["Data"])
+	}
+}
+
+func TestSynthesizeID(t *testing.T) {
+	if id := synthesizeID("GET", "/collections/{collectionId}/items/{featureId}"); id != "getCollectionsCollectionIdItemsFeatureId" {
+		t.Error(id)
+	}
+	if id := synthesizeID("DELETE", "/"); id != "delete" {
+		t.Error(id)
+	}
+}
@@ -0,0 +1,334 @@
+package wfs
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jban332/kin-openapi/openapi3"
+)
+
+// Pages iterates over the pages of a collection items request by following
+// the "next" links of each response.
+//
+// With Parallel, pages are requested concurrently when the first response
+// reports numberMatched and the items operation declares a startIndex or
+// offset parameter. Pages are still returned in order.
+//
+//	pages := coll.Pages(ctx, q)
+//	for pages.Next() {
+//		fc := pages.Page()
+//		...
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+type Pages struct {
+	ctx    context.Context
+	coll   Collection
+	q      ItemsQuery
+	local  ItemsQuery
+	values url.Values
+	next   string
+	resume string
+	first  *http.Request
+	page   FeatureCollection
+	err    error
+	done   bool
+
+	fetched  int
+	parallel int
+	to       string
+	ahead    []chan pageResult
+	index    int
+	tokens   chan struct{}
+	cancel   context.CancelFunc
+}
+
+type pageResult struct {
+	page FeatureCollection
+	err  error
+}
+
+// Parallel sets the number of pages requested concurrently, if the server
+// allows computing page offsets. It must be called before the first Next.
+// Requests are further bounded by the per-host limit of the Client. The
+// iteration must be completed or its context cancelled to release the
+// requests in flight.
+func (p *Pages) Parallel(n int) *Pages {
+	p.parallel = n
+	return p
+}
+
+// Reproject transforms the geometries of each page to the CRS identified by
+// to as it is fetched. An empty to keeps the CRS of the responses.
+func (p *Pages) Reproject(to string) *Pages {
+	p.to = to
+	return p
+}
+
+// Pages returns an iterator over all pages matching q.
+func (c Collection) Pages(ctx context.Context, q ItemsQuery) *Pages {
+	return &Pages{ctx: ctx, coll: c, q: q}
+}
+
+// ResumePages works as per Pages but starts at next, a URL previously
+// returned by NextURL, so an interrupted iteration can be continued. An empty
+// next starts at the first page.
+func (c Collection) ResumePages(ctx context.Context, q ItemsQuery, next string) *Pages {
+	return &Pages{ctx: ctx, coll: c, q: q, resume: next}
+}
+
+// Pages returns an iterator over the feature collection pages returned by
+// the Call, starting with its own response. The first page is requested with
+// the method, body and media types of the Call, the following ones with GET.
+func (c Call) Pages(ctx context.Context) *Pages {
+	p := &Pages{ctx: ctx, coll: Collection{svc: c.op.svc}}
+	req, _, err := c.request()
+	if err != nil {
+		p.fail(err)
+		return p
+	}
+	p.first = req.WithContext(ctx)
+	p.next = req.URL.String()
+	return p
+}
+
+// Next requests the next page, returning false when there are no more pages
+// or an error occurred.
+func (p *Pages) Next() bool {
+	if p.done {
+		return false
+	}
+	if p.ahead != nil {
+		return p.nextAhead()
+	}
+	if p.next == "" {
+		values, local, err := p.coll.prepare(p.ctx, p.q)
+		if err != nil {
+			return p.fail(err)
+		}
+		p.values, p.local = values, local
+		if p.next, err = p.coll.svc.paths.collectionItems(p.coll.Name); err != nil {
+			return p.fail(err)
+		}
+		if p.resume != "" {
+			// the next link carries the complete query
+			p.values, p.next = nil, p.resume
+		}
+	}
+	current := p.next
+	first := p.values
+	isFirst := p.fetched == 0 && p.resume == "" && p.coll.Name != ""
+	p.fetched++
+	page, err := p.fetch(current)
+	if err != nil {
+		return p.fail(err)
+	}
+	if !p.setPage(page) {
+		return false
+	}
+	if isFirst && p.parallel > 1 && p.startAhead(current, first, page) {
+		return true
+	}
+	// the next link carries the complete query
+	p.values = nil
+	p.next = nextLink(current, page.Links)
+	if p.next == "" || p.next == current || len(page.Features) == 0 {
+		p.done = true
+	}
+	return true
+}
+
+func (p *Pages) fail(err error) bool {
+	p.err = err
+	p.done = true
+	if p.cancel != nil {
+		p.cancel()
+	}
+	return false
+}
+
+// fetch requests the page at u, sending the request of a Call as is.
+func (p *Pages) fetch(u string) (FeatureCollection, error) {
+	if req := p.first; req != nil {
+		p.first = nil
+		body, header, err := p.coll.svc.cl.fetch(req)
+		if err != nil {
+			return FeatureCollection{}, err
+		}
+		return decodePage(u, body, header, p.q, p.local)
+	}
+	return p.coll.page(p.ctx, u, p.values, p.q, p.local)
+}
+
+// setPage makes page the current page, reprojecting it if requested.
+func (p *Pages) setPage(page FeatureCollection) bool {
+	if p.to != "" {
+		if err := page.Reproject(p.to); err != nil {
+			return p.fail(err)
+		}
+	}
+	p.page = page
+	return true
+}
+
+// startAhead starts fetching the remaining pages concurrently by offset,
+// reporting false if the offsets cannot be computed.
+func (p *Pages) startAhead(u string, first url.Values, page FeatureCollection) bool {
+	param := p.coll.offsetParam()
+	// servers may return fewer features than the requested limit, step by
+	// what the first page held
+	limit := p.q.Limit
+	if n := len(page.Features); limit == 0 || n < limit {
+		limit = n
+	}
+	if param == "" || page.NumberMatched == 0 || limit == 0 {
+		return false
+	}
+	start := 0
+	if v, err := strconv.Atoi(first.Get(param)); err == nil {
+		start = v
+	}
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.cancel = cancel
+	p.tokens = make(chan struct{}, p.parallel)
+	for off := start + len(page.Features); off < page.NumberMatched; off += limit {
+		p.ahead = append(p.ahead, make(chan pageResult, 1))
+	}
+	if len(p.ahead) == 0 {
+		p.done = true
+		cancel()
+		return true
+	}
+	go func() {
+		for i, off := 0, start+len(page.Features); i < len(p.ahead); i, off = i+1, off+limit {
+			select {
+			case p.tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			values := url.Values{}
+			for k, v := range first {
+				values[k] = v
+			}
+			values.Set(param, strconv.Itoa(off))
+			values.Set("limit", strconv.Itoa(limit))
+			go func(i int, values url.Values) {
+				page, err := p.coll.page(ctx, u, values, p.q, p.local)
+				p.ahead[i] <- pageResult{page, err}
+			}(i, values)
+		}
+	}()
+	return true
+}
+
+// nextAhead returns the next of the concurrently fetched pages.
+func (p *Pages) nextAhead() bool {
+	var r pageResult
+	select {
+	case r = <-p.ahead[p.index]:
+	case <-p.ctx.Done():
+		return p.fail(p.ctx.Err())
+	}
+	<-p.tokens
+	if r.err != nil {
+		return p.fail(r.err)
+	}
+	if !p.setPage(r.page) {
+		return false
+	}
+	p.index++
+	if p.index == len(p.ahead) {
+		p.done = true
+		p.cancel()
+	}
+	return true
+}
+
+// Page returns the current page.
+func (p *Pages) Page() FeatureCollection {
+	return p.page
+}
+
+// NextURL returns the URL of the page following the current one, or "" if
+// it is the last. It may be persisted to continue with ResumePages. It is
+// not available while pages are fetched in parallel.
+func (p *Pages) NextURL() string {
+	if p.done || p.ahead != nil {
+		return ""
+	}
+	return p.next
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (p *Pages) Err() error {
+	return p.err
+}
+
+// nextLink returns the resolved href of the "next" link, or "".
+func nextLink(base string, links []Link) string {
+	for _, l := range links {
+		if l.Rel != "next" {
+			continue
+		}
+		b, err := url.Parse(base)
+		if err != nil {
+			return ""
+		}
+		ref, err := url.Parse(l.Href)
+		if err != nil {
+			return ""
+		}
+		return b.ResolveReference(ref).String()
+	}
+	return ""
+}
+
+// offsetParam returns the name of the paging offset query parameter,
+// startIndex or offset, declared by the items operation of the collection,
+// or "" if there is none.
+func (c Collection) offsetParam() string {
+	if c.svc.spec == nil {
+		return ""
+	}
+	u, err := c.svc.paths.collectionItems(c.Name)
+	if err != nil {
+		return ""
+	}
+	items, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	rel := strings.TrimPrefix(items.Path, c.svc.paths.root.Path)
+	for tmpl, item := range c.svc.spec.Paths {
+		if item == nil || item.Get == nil || !matchPathTemplate(tmpl, rel) {
+			continue
+		}
+		params := append(append(openapi3.Parameters{}, item.Parameters...), item.Get.Parameters...)
+		for _, ref := range params {
+			if p := ref.Value; p != nil && p.In == "query" && (p.Name == "startIndex" || p.Name == "offset") {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+// matchPathTemplate reports whether path matches an OpenAPI path template,
+// where each "{name}" segment matches any single segment.
+func matchPathTemplate(tmpl, path string) bool {
+	ts := strings.Split(strings.Trim(tmpl, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	if len(ts) != len(ps) {
+		return false
+	}
+	for i, t := range ts {
+		if !(strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}")) && t != ps[i] {
+			return false
+		}
+	}
+	return true
+}
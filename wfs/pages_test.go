@@ -0,0 +1,217 @@
+package wfs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const pagedSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/collections/{collectionId}/items": {
+      "parameters": [{"name": "collectionId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {
+        "operationId": "getFeatures",
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer"}},
+          {"name": "startIndex", "in": "query", "schema": {"type": "integer"}}
+        ],
+        "responses": {"200": {"description": "features"}}
+      }
+    }
+  }
+}`
+
+func TestParallelPages(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive, requests := 0, 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/":
+			w.Write([]byte(pagedSpec))
+			return
+		case "/collections/c/items/":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		active++
+		requests++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "numberMatched": 9, "links": [{"rel": "next", "href": "?startIndex=%d&limit=2"}],
+			"features": [{"type": "Feature", "id": %d, "properties": {}, "geometry": null}`, start+2, start)
+		if start+1 < 9 {
+			fmt.Fprintf(w, `, {"type": "Feature", "id": %d, "properties": {}, "geometry": null}`, start+1)
+		}
+		fmt.Fprint(w, "]}")
+	}))
+	defer srv.Close()
+	svc, err := NewClient(srv.Client()).WithHostLimit(2).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := svc.Collection("c").Pages(context.Background(), ItemsQuery{Limit: 2}).Parallel(4)
+	next := 0
+	for pages.Next() {
+		for _, f := range pages.Page().Features {
+			if f.ID != float64(next) {
+				t.Fatalf("feature %v, expected %d", f.ID, next)
+			}
+			next++
+		}
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if next != 9 || requests != 5 {
+		t.Errorf("features %d requests %d", next, requests)
+	}
+	if maxActive != 2 {
+		t.Errorf("concurrent requests %d", maxActive)
+	}
+}
+
+func TestParallelPagesCapped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/":
+			w.Write([]byte(pagedSpec))
+			return
+		case "/collections/c/items/":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		// the server caps the limit at 2
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit == 0 || limit > 2 {
+			limit = 2
+		}
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "numberMatched": 20, "links": [{"rel": "next", "href": "?startIndex=%d&limit=2"}], "features": [`, start+limit)
+		for i := start; i < start+limit && i < 20; i++ {
+			if i > start {
+				fmt.Fprint(w, ", ")
+			}
+			fmt.Fprintf(w, `{"type": "Feature", "id": %d, "properties": {}, "geometry": null}`, i)
+		}
+		fmt.Fprint(w, "]}")
+	}))
+	defer srv.Close()
+	svc, err := NewClient(srv.Client()).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := svc.Collection("c").Pages(context.Background(), ItemsQuery{Limit: 5}).Parallel(4)
+	next := 0
+	for pages.Next() {
+		for _, f := range pages.Page().Features {
+			if f.ID != float64(next) {
+				t.Fatalf("feature %v, expected %d", f.ID, next)
+			}
+			next++
+		}
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if next != 20 {
+		t.Errorf("features %d", next)
+	}
+}
+
+func TestPagesReproject(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next := ""
+		if r.URL.Query().Get("page") == "" {
+			next = `{"rel": "next", "href": "?page=2"}`
+		}
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "links": [%s],
+			"features": [{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [180, 0]}}]}`, next)
+	}))
+	defer srv.Close()
+	coll := testService(srv, nil).Collection("c")
+	pages := coll.Pages(context.Background(), ItemsQuery{}).Reproject("EPSG:3857")
+	n := 0
+	for pages.Next() {
+		n++
+		if p := pages.Page().Features[0].Geometry.Point; p[0] < 20037508 || p[0] > 20037509 {
+			t.Errorf("page %d point %v", n, p)
+		}
+	}
+	if err := pages.Err(); err != nil || n != 2 {
+		t.Errorf("%d pages, %v", n, err)
+	}
+}
+
+const searchSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/search": {
+      "post": {
+        "operationId": "search",
+        "requestBody": {"content": {"application/json": {}}},
+        "responses": {"200": {"description": "features", "content": {"application/geo+json": {}}}}
+      }
+    }
+  }
+}`
+
+func TestCallPages(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Accept")+" "+string(b))
+		next := ""
+		if r.Method == "POST" {
+			next = `{"rel": "next", "href": "/search?page=2"}`
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		fmt.Fprintf(w, `{"type": "FeatureCollection", "links": [%s],
+			"features": [{"type": "Feature", "properties": {}, "geometry": null}]}`, next)
+	}))
+	defer srv.Close()
+	spec, err := parseSpec([]byte(searchSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := testService(srv, spec)
+	op, err := svc.GetOperation("search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, _ := op.Call(nil)
+	if call, err = call.WithBody("json", []byte(`{"limit": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	pages := call.Pages(context.Background())
+	n := 0
+	for pages.Next() {
+		n += len(pages.Page().Features)
+	}
+	if err := pages.Err(); err != nil || n != 2 {
+		t.Errorf("%d features, %v", n, err)
+	}
+	if len(requests) != 2 || requests[0] != `POST /search application/geo+json {"limit": 1}` || !strings.HasPrefix(requests[1], "GET /search?page=2 ") {
+		t.Errorf("requests %q", requests)
+	}
+}
@@ -0,0 +1,158 @@
+package wfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Logger receives the warnings of a Client. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// stdLogger writes to the standard logger of the log package.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+// PatchReport lists the component references of a spec that did not
+// resolve within the document and those filled in from fallback components.
+type PatchReport struct {
+	Unresolved []string
+	Patched    []string
+}
+
+// componentPatch configures the repair of specs referencing components they
+// do not define. Fallbacks are JSON components objects tried in order.
+type componentPatch struct {
+	disabled  bool
+	fallbacks [][]byte
+}
+
+// builtinPatch supplies the WFS3 components of the draft specification,
+// which early servers referenced without including them.
+var builtinPatch = componentPatch{fallbacks: [][]byte{[]byte(_componentsJSON)}}
+
+// componentPatch returns the patching configured for the Client.
+func (c Client) componentPatch() componentPatch {
+	if c.patch == nil {
+		return builtinPatch
+	}
+	return *c.patch
+}
+
+// patchComponents adds to doc the components that its "#/components/..."
+// references point to but that it does not define, taking them from the
+// fallbacks. Added components may reference further ones, which are added as
+// well. References that cannot be patched are returned as an error.
+func (cp componentPatch) patchComponents(doc map[string]interface{}) (PatchReport, error) {
+	var report PatchReport
+	var fallbacks []map[string]interface{}
+	if !cp.disabled {
+		for i, b := range cp.fallbacks {
+			var comps map[string]interface{}
+			if err := json.Unmarshal(b, &comps); err != nil {
+				return report, fmt.Errorf("invalid fallback components %d : %s", i+1, err)
+			}
+			fallbacks = append(fallbacks, comps)
+		}
+	}
+	components, _ := doc["components"].(map[string]interface{})
+	if components == nil {
+		components = map[string]interface{}{}
+	}
+	var failed []string
+	done := map[string]bool{}
+	pending := componentRefs(doc)
+	for len(pending) > 0 {
+		ref := pending[0]
+		pending = pending[1:]
+		if done[ref] {
+			continue
+		}
+		done[ref] = true
+		kind, name, ok := splitComponentRef(ref)
+		if !ok || lookupComponent(components, kind, name) != nil {
+			continue
+		}
+		report.Unresolved = append(report.Unresolved, ref)
+		var found interface{}
+		for _, fb := range fallbacks {
+			if found = lookupComponent(fb, kind, name); found != nil {
+				break
+			}
+		}
+		if found == nil {
+			failed = append(failed, ref)
+			continue
+		}
+		section, _ := components[kind].(map[string]interface{})
+		if section == nil {
+			section = map[string]interface{}{}
+			components[kind] = section
+		}
+		section[name] = found
+		report.Patched = append(report.Patched, ref)
+		pending = append(pending, componentRefs(found)...)
+	}
+	if len(report.Patched) > 0 {
+		doc["components"] = components
+	}
+	sort.Strings(report.Unresolved)
+	sort.Strings(report.Patched)
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return report, fmt.Errorf("unresolved references %s", strings.Join(failed, ", "))
+	}
+	return report, nil
+}
+
+// componentRefs returns the local component references found in v, in
+// document order of a depth first walk with sorted keys.
+func componentRefs(v interface{}) []string {
+	var refs []string
+	var walk func(interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, "#/components/") {
+				refs = append(refs, ref)
+			}
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[k])
+			}
+		}
+	}
+	walk(v)
+	return refs
+}
+
+// splitComponentRef splits "#/components/<kind>/<name>" into its parts,
+// decoding JSON pointer escapes.
+func splitComponentRef(ref string) (string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	return unescape.Replace(parts[0]), unescape.Replace(parts[1]), true
+}
+
+func lookupComponent(components map[string]interface{}, kind, name string) interface{} {
+	section, _ := components[kind].(map[string]interface{})
+	return section[name]
+}
@@ -0,0 +1,102 @@
+package wfs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const patchSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "paths": {
+    "/collections/{collectionId}/items": {
+      "get": {
+        "operationId": "getFeatures",
+        "parameters": [
+          {"$ref": "#/components/parameters/count"},
+          {"$ref": "#/components/parameters/bbox"},
+          {"$ref": "#/components/parameters/defined"}
+        ],
+        "responses": {"200": {"description": "features"}}
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "defined": {"name": "defined", "in": "query", "schema": {"type": "string"}}
+    }
+  }
+}`
+
+type testLogger []string
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func patchServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(patchSpec))
+	}))
+}
+
+func TestComponentPatching(t *testing.T) {
+	srv := patchServer()
+	defer srv.Close()
+	var logged testLogger
+	cl := NewClient(srv.Client()).WithLogger(&logged)
+
+	svc, err := cl.Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := svc.PatchReport()
+	want := "#/components/parameters/bbox,#/components/parameters/count"
+	if strings.Join(report.Unresolved, ",") != want || strings.Join(report.Patched, ",") != want {
+		t.Errorf("report %+v", report)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "#/components/parameters/count") {
+		t.Errorf("logged %q", logged)
+	}
+	op, err := svc.GetOperation("getFeatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(op.Params) != 3 || op.Params[0].Name != "count" || op.Params[2].Name != "defined" {
+		t.Errorf("params %+v", op.Params)
+	}
+
+	// fallbacks may reference further components, which are patched too
+	custom := cl.WithFallbackComponents(
+		[]byte(`{"parameters": {"count": {"name": "limit", "in": "query", "schema": {"$ref": "#/components/schemas/limit"}}}}`),
+		[]byte(`{"parameters": {"bbox": {"name": "bbox", "in": "query", "schema": {"type": "string"}}},
+			"schemas": {"limit": {"type": "integer"}}}`),
+	)
+	svc, err = custom.Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := svc.PatchReport().Patched; len(p) != 3 || p[2] != "#/components/schemas/limit" {
+		t.Errorf("patched %v", p)
+	}
+	op, _ = svc.GetOperation("getFeatures")
+	if op.Params[0].Name != "limit" || op.Params[0].Type != "integer" {
+		t.Errorf("custom param %+v", op.Params[0])
+	}
+
+	_, err = cl.WithoutPatching().Connect(srv.URL, false)
+	if err == nil || !strings.Contains(err.Error(), "unresolved references #/components/parameters/bbox, #/components/parameters/count") {
+		t.Errorf("expected unresolved references, got %v", err)
+	}
+	_, err = cl.WithFallbackComponents([]byte(`{}`)).Connect(srv.URL, false)
+	if err == nil {
+		t.Error("expected error when fallbacks lack the components")
+	}
+}
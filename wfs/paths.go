@@ -1,8 +1,10 @@
 package wfs
 
 import (
+	"fmt"
 	"net/url"
 	"path"
+	"strings"
 )
 
 type pathStyle string
@@ -34,47 +36,68 @@ type pather struct {
 	style pathStyle
 }
 
-func (p pather) url(paths ...string) string {
+// url resolves the joined path segments against the root, with a trailing
+// slash. No segments yields the root itself.
+func (p pather) url(paths ...string) (string, error) {
+	if p.root == nil {
+		return "", fmt.Errorf("no service root")
+	}
 	if len(paths) == 0 {
-		return p.root.String()
+		return p.root.String(), nil
 	}
-	rel, err := url.Parse(path.Join(paths...) + "/")
+	// the leading ./ keeps a colon in a name from being read as a scheme
+	rel, err := url.Parse("./" + path.Join(paths...) + "/")
 	if err != nil {
-		panic(err)
+		return "", fmt.Errorf("invalid path %q under %s : %s", path.Join(paths...), p.root, err)
 	}
-	return p.root.ResolveReference(rel).String()
+	return p.root.ResolveReference(rel).String(), nil
 }
 
-func (p pather) collectionInfo() string {
+// styled builds the URL from the path segments of the style in use.
+func (p pather) styled(old, new []string) (string, error) {
 	switch p.style {
 	case oldStylePaths:
-		return p.url()
+		return p.url(old...)
 	case newStylePaths:
-		return p.url("collections")
+		return p.url(new...)
 	}
-	panic("path style")
+	return "", fmt.Errorf("unknown path style %q", p.style)
+}
+
+func (p pather) collectionInfo() (string, error) {
+	return p.styled(nil, []string{"collections"})
 }
 
-func (p pather) spec() string {
+func (p pather) spec() (string, error) {
 	return p.url("api")
 }
 
-func (p pather) collectionItems(cid string) string {
-	switch p.style {
-	case oldStylePaths:
-		return p.url(cid)
-	case newStylePaths:
-		return p.url("collections", cid, "items")
-	}
-	panic("path style")
+func (p pather) collectionItems(cid string) (string, error) {
+	return p.styled([]string{cid}, []string{"collections", cid, "items"})
 }
 
-func (p pather) collectionItem(cid, fid string) string {
-	switch p.style {
-	case oldStylePaths:
-		return p.url(cid, fid)
-	case newStylePaths:
-		return p.url("collections", cid, "items", fid)
+// collectionItem escapes fid so that IDs containing slashes, spaces or
+// query characters address a single path segment. The dots of "." and ".."
+// are escaped too, as they would otherwise address the parent path.
+func (p pather) collectionItem(cid, fid string) (string, error) {
+	if fid == "" {
+		return "", fmt.Errorf("empty feature ID")
+	}
+	fid = url.PathEscape(fid)
+	if strings.Trim(fid, ".") == "" {
+		fid = strings.Replace(fid, ".", "%2E", -1)
 	}
-	panic("path style")
+	return p.styled([]string{cid, fid}, []string{"collections", cid, "items", fid})
+}
+
+func (p pather) conformance() (string, error) {
+	return p.url("conformance")
+}
+
+func (p pather) queryables(cid string) (string, error) {
+	return p.styled([]string{cid, "queryables"}, []string{"collections", cid, "queryables"})
+}
+
+func (p pather) schema(cid string) (string, error) {
+	return p.styled([]string{cid, "schema"}, []string{"collections", cid, "schema"})
 }
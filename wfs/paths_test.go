@@ -10,18 +10,35 @@ func TestPather(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
+	check := func(name, want string) func(string, error) {
+		return func(got string, err error) {
+			if err != nil || got != want {
+				t.Errorf("%s %s %v", name, got, err)
+			}
+		}
+	}
 	old := pather{u, oldStylePaths}
-	if p := old.spec(); p != "http://server.domain/path/api/" {
-		t.Errorf("old spec %s", p)
+	check("old spec", "http://server.domain/path/api/")(old.spec())
+	check("old collection info", "http://server.domain/path/")(old.collectionInfo())
+	check("old collection items", "http://server.domain/path/c/")(old.collectionItems("c"))
+	check("old collection item", "http://server.domain/path/c/f/")(old.collectionItem("c", "f"))
+	newer := pather{u, newStylePaths}
+	check("escaped collection item", "http://server.domain/path/collections/c/items/a%2Fb%20c%3F/")(newer.collectionItem("c", "a/b c?"))
+	check("dot item", "http://server.domain/path/collections/c/items/%2E%2E/")(newer.collectionItem("c", ".."))
+	check("old dot item", "http://server.domain/path/c/%2E/")(old.collectionItem("c", "."))
+	check("dotted item", "http://server.domain/path/c/a.b/")(old.collectionItem("c", "a.b"))
+	if _, err := newer.collectionItem("c", ""); err == nil {
+		t.Error("expected error for empty feature ID")
 	}
-	if p := old.collectionInfo(); p != "http://server.domain/path/" {
-		t.Errorf("old collection info %s", p)
+
+	if _, err := (pather{u, pathStyle("other")}).collectionItems("c"); err == nil {
+		t.Error("expected error for unknown path style")
 	}
-	if p := old.collectionItems("c"); p != "http://server.domain/path/c/" {
-		t.Errorf("old collection items %s", p)
+	check("collection with colon", "http://server.domain/path/a:b/")(old.collectionItems("a:b"))
+	if _, err := old.collectionItems("%zz"); err == nil {
+		t.Error("expected error for invalid collection name")
 	}
-	if p := old.collectionItem("c", "f"); p != "http://server.domain/path/c/f/" {
-		t.Errorf("old collection item %s", p)
+	if _, err := (pather{nil, newStylePaths}).conformance(); err == nil {
+		t.Error("expected error without root")
 	}
-
 }
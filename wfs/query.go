@@ -0,0 +1,255 @@
+package wfs
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortField is a single key of a sortby expression.
+type SortField struct {
+	Property   string
+	Descending bool
+}
+
+func (s SortField) String() string {
+	if s.Descending {
+		return "-" + s.Property
+	}
+	return "+" + s.Property
+}
+
+// ParseSortBy parses a comma separated sortby expression such as
+// "name,-population". Properties without a sign sort ascending.
+func ParseSortBy(expr string) []SortField {
+	fields := []SortField{}
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		f := SortField{}
+		switch part[0] {
+		case '-':
+			f.Descending = true
+			part = part[1:]
+		case '+':
+			part = part[1:]
+		}
+		f.Property = part
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// ItemsQuery holds the options of a collection items request. The zero value
+// requests the server defaults.
+//
+// BBox is always given in x, y (longitude or easting first) order and is
+// swapped as needed when BBoxCrs uses latitude first axis order.
+type ItemsQuery struct {
+	Limit   int
+	BBox    *BBox
+	BBoxCrs string
+	Crs     string
+	// Datetime is an instant or interval such as "2018-02-12T00:00:00Z/.."
+	// selecting features by their temporal property.
+	Datetime     string
+	Properties   []string
+	SortBy       []SortField
+	SkipGeometry bool
+	// Format is the short or full name of the MediaType to request. If empty,
+	// GeoJSON is preferred over JSON and GML. GML responses are decoded with
+	// DecodeGML.
+	Format string
+}
+
+// Validate checks that every property referenced by the query is one of the
+// given queryables.
+func (q ItemsQuery) Validate(queryables []string) error {
+	known := map[string]bool{}
+	for _, name := range queryables {
+		known[name] = true
+	}
+	for _, p := range q.Properties {
+		if !known[p] {
+			return fmt.Errorf("property %q is not queryable", p)
+		}
+	}
+	for _, s := range q.SortBy {
+		if !known[s.Property] {
+			return fmt.Errorf("sortby property %q is not queryable", s.Property)
+		}
+	}
+	return nil
+}
+
+// validateCrs checks that Crs and BBoxCrs are among the CRS supported by a
+// collection, as given by its crs list and storageCrs. CRS84 is always
+// supported and a collection listing no CRS is not checked.
+func (q ItemsQuery) validateCrs(info CollectionInfo) error {
+	known := map[string]bool{CRS84: true}
+	for _, c := range info.Crs {
+		if c == "#/crs" {
+			// refers to the list of the collections document
+			return nil
+		}
+		known[CRSURI(c)] = true
+	}
+	if len(info.Crs) == 0 {
+		return nil
+	}
+	if info.StorageCrs != "" {
+		known[CRSURI(info.StorageCrs)] = true
+	}
+	for _, c := range []string{q.Crs, q.BBoxCrs} {
+		if c != "" && !known[CRSURI(c)] {
+			return fmt.Errorf("CRS %q is not supported by collection %q", c, info.Name)
+		}
+	}
+	return nil
+}
+
+// values encodes the query options supported by the server as declared by
+// conf. It also returns the query holding the options that must be applied
+// locally instead.
+func (q ItemsQuery) values(conf Conformance) (url.Values, ItemsQuery) {
+	v := url.Values{}
+	local := ItemsQuery{}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.BBox != nil {
+		b := *q.BBox
+		if LatitudeFirst(q.BBoxCrs) {
+			b = BBox{b[1], b[0], b[3], b[2]}
+		}
+		parts := make([]string, len(b))
+		for i, c := range b {
+			parts[i] = strconv.FormatFloat(c, 'f', -1, 64)
+		}
+		v.Set("bbox", strings.Join(parts, ","))
+		if q.BBoxCrs != "" {
+			v.Set("bbox-crs", CRSURI(q.BBoxCrs))
+		}
+	}
+	if q.Crs != "" {
+		v.Set("crs", CRSURI(q.Crs))
+	}
+	if q.Datetime != "" {
+		v.Set("datetime", q.Datetime)
+	}
+	sortLocal := len(q.SortBy) > 0 && !conf.ConformsTo(ConformanceSorting)
+	if len(q.Properties) > 0 {
+		if conf.ConformsTo(ConformancePropertySelection) {
+			props := append([]string{}, q.Properties...)
+			if sortLocal {
+				// the local sort needs its keys, they are stripped after
+				// sorting
+				for _, s := range q.SortBy {
+					if !hasString(props, s.Property) {
+						props = append(props, s.Property)
+						local.Properties = q.Properties
+					}
+				}
+			}
+			v.Set("properties", strings.Join(props, ","))
+		} else {
+			local.Properties = q.Properties
+		}
+	}
+	if len(q.SortBy) > 0 {
+		if !sortLocal {
+			keys := make([]string, len(q.SortBy))
+			for i, s := range q.SortBy {
+				keys[i] = s.String()
+			}
+			v.Set("sortby", strings.Join(keys, ","))
+		} else {
+			local.SortBy = q.SortBy
+		}
+	}
+	if q.SkipGeometry {
+		if conf.ConformsTo(ConformanceSkipGeometry) {
+			v.Set("skipGeometry", "true")
+		} else {
+			local.SkipGeometry = true
+		}
+	}
+	return v, local
+}
+
+// apply sorts, projects and strips the features of fc in place. It is the
+// client-side fallback for options the server does not support. Note that
+// sorting only applies to the features of the page at hand, and that it
+// happens before the projection, which may drop the sort keys.
+func (q ItemsQuery) apply(fc *FeatureCollection) {
+	if len(q.SortBy) > 0 {
+		sort.SliceStable(fc.Features, func(i, j int) bool {
+			a, b := fc.Features[i].Properties, fc.Features[j].Properties
+			for _, s := range q.SortBy {
+				c := compareValues(a[s.Property], b[s.Property])
+				if c == 0 {
+					continue
+				}
+				if s.Descending {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+	for i := range fc.Features {
+		f := &fc.Features[i]
+		if len(q.Properties) > 0 {
+			props := make(map[string]interface{}, len(q.Properties))
+			for _, p := range q.Properties {
+				if v, ok := f.Properties[p]; ok {
+					props[p] = v
+				}
+			}
+			f.Properties = props
+		}
+		if q.SkipGeometry {
+			f.Geometry = nil
+		}
+	}
+}
+
+// hasString reports whether list holds s.
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compareValues orders decoded JSON values. Missing values sort first,
+// numbers compare numerically and everything else by its string form.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	af, aok := a.(float64)
+	bf, bok := b.(float64)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
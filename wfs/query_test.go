@@ -0,0 +1,85 @@
+package wfs
+
+import (
+	"testing"
+)
+
+func TestParseSortBy(t *testing.T) {
+	fields := ParseSortBy("name, -population,+rank,")
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %v", fields)
+	}
+	if f := fields[0]; f.Property != "name" || f.Descending {
+		t.Errorf("first field %v", f)
+	}
+	if f := fields[1]; f.Property != "population" || !f.Descending {
+		t.Errorf("second field %v", f)
+	}
+	if f := fields[2]; f.String() != "+rank" {
+		t.Errorf("third field %v", f)
+	}
+}
+
+func TestItemsQueryValidate(t *testing.T) {
+	q := ItemsQuery{Properties: []string{"name"}, SortBy: ParseSortBy("-pop")}
+	if err := q.Validate([]string{"name", "pop"}); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := q.Validate([]string{"name"}); err == nil {
+		t.Errorf("expected sortby error")
+	}
+}
+
+func TestItemsQueryValues(t *testing.T) {
+	q := ItemsQuery{Limit: 5, Properties: []string{"a", "b"}, SortBy: ParseSortBy("-a"), SkipGeometry: true}
+	conf := Conformance{"http://www.opengis.net/spec/ogcapi-features-8/1.0/conf/sorting"}
+	v, local := q.values(conf)
+	if s := v.Encode(); s != "limit=5&sortby=-a" {
+		t.Errorf("values %s", s)
+	}
+	if len(local.Properties) != 2 || local.SortBy != nil || !local.SkipGeometry {
+		t.Errorf("local %v", local)
+	}
+
+	// the server selects properties, so the local sort key must be requested
+	// and stripped again
+	q = ItemsQuery{Properties: []string{"a"}, SortBy: ParseSortBy("-b")}
+	v, local = q.values(Conformance{"http://www.opengis.net/spec/ogcapi-features-6/1.0/conf/property-selection"})
+	if s := v.Get("properties"); s != "a,b" {
+		t.Errorf("properties %s", s)
+	}
+	if len(local.Properties) != 1 || len(local.SortBy) != 1 {
+		t.Errorf("local %v", local)
+	}
+}
+
+func TestItemsQueryApply(t *testing.T) {
+	fc := FeatureCollection{Features: []Feature{
+		{Geometry: &Geometry{Type: "Point", Point: Position{1, 2}}, Properties: map[string]interface{}{"n": "b", "v": 2.0, "x": 1}},
+		{Properties: map[string]interface{}{"n": "a", "v": 10.0}},
+		{Properties: map[string]interface{}{"n": "c"}},
+	}}
+	ItemsQuery{Properties: []string{"n", "v"}, SortBy: ParseSortBy("-v"), SkipGeometry: true}.apply(&fc)
+	order := ""
+	for _, f := range fc.Features {
+		order += f.Properties["n"].(string)
+		if f.Geometry != nil {
+			t.Errorf("geometry not skipped")
+		}
+		if _, ok := f.Properties["x"]; ok {
+			t.Errorf("property not projected")
+		}
+	}
+	if order != "abc" {
+		t.Errorf("order %s", order)
+	}
+
+	fc = FeatureCollection{Features: []Feature{
+		{Properties: map[string]interface{}{"name": "a", "pop": 1.0}},
+		{Properties: map[string]interface{}{"name": "b", "pop": 2.0}},
+	}}
+	ItemsQuery{Properties: []string{"name"}, SortBy: ParseSortBy("-pop")}.apply(&fc)
+	if a, b := fc.Features[0].Properties, fc.Features[1].Properties; a["name"] != "b" || b["name"] != "a" || len(a) != 1 {
+		t.Errorf("sorted by a property not selected %v %v", a, b)
+	}
+}
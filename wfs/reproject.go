@@ -0,0 +1,82 @@
+package wfs
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ischneider/go-wfs-client/proj"
+)
+
+// transformer returns a function transforming positions between the CRS
+// identified by from and to. An empty from denotes CRS84.
+func transformer(from, to string) (func(Position) Position, error) {
+	if from == "" {
+		from = CRS84
+	}
+	src, ok := EPSGCode(from)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CRS %q", from)
+	}
+	dst, ok := EPSGCode(to)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CRS %q", to)
+	}
+	t, err := proj.NewTransformer(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	return func(p Position) Position {
+		if len(p) >= 2 {
+			p[0], p[1] = t.Transform(p[0], p[1])
+		}
+		return p
+	}, nil
+}
+
+// Reproject transforms the geometries of the page from its Crs to the CRS
+// identified by to and updates Crs accordingly.
+func (fc *FeatureCollection) Reproject(to string) error {
+	fn, err := transformer(fc.Crs, to)
+	if err != nil {
+		return err
+	}
+	for _, f := range fc.Features {
+		f.Geometry.Transform(fn)
+	}
+	fc.Crs = CRSURI(to)
+	return nil
+}
+
+// Reproject transforms the geometry of the Feature between the CRS
+// identified by from and to.
+func (f *Feature) Reproject(from, to string) error {
+	fn, err := transformer(from, to)
+	if err != nil {
+		return err
+	}
+	f.Geometry.Transform(fn)
+	return nil
+}
+
+// Reproject returns the bounds of b, given in the CRS identified by from, in
+// the CRS identified by to. The edges are densified so that the result
+// covers the curved outline of the transformed box.
+func (b BBox) Reproject(from, to string) (BBox, error) {
+	fn, err := transformer(from, to)
+	if err != nil {
+		return BBox{}, err
+	}
+	const steps = 16
+	out := BBox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for i := 0; i <= steps; i++ {
+		f := float64(i) / steps
+		x := b[0] + f*(b[2]-b[0])
+		y := b[1] + f*(b[3]-b[1])
+		for _, p := range []Position{{x, b[1]}, {x, b[3]}, {b[0], y}, {b[2], y}} {
+			p = fn(p)
+			out[0], out[1] = math.Min(out[0], p[0]), math.Min(out[1], p[1])
+			out[2], out[3] = math.Max(out[2], p[0]), math.Max(out[3], p[1])
+		}
+	}
+	return out, nil
+}
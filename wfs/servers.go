@@ -0,0 +1,154 @@
+package wfs
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jban332/kin-openapi/openapi3"
+)
+
+// serverSelection configures which of the servers declared by a spec are
+// used and how their URL templates are filled in.
+type serverSelection struct {
+	// selector is an index into the servers or a part of a description,
+	// empty for the first server
+	selector string
+	vars     map[string]string
+}
+
+// WithServer returns a Client that uses the server of a spec selected by
+// its index, counted from 0, or by a case-insensitive part of its
+// description. Path and operation level servers are selected the same way,
+// falling back to their first entry if none matches.
+func (c Client) WithServer(selector string) Client {
+	c.servers.selector = selector
+	return c
+}
+
+// WithServerVariables returns a Client that substitutes vars for the
+// variables of server URL templates. Variables not given take the default
+// declared by the spec.
+func (c Client) WithServerVariables(vars map[string]string) Client {
+	c.servers.vars = vars
+	return c
+}
+
+// pick returns the selected server, nil if servers is empty. If strict, a
+// selector matching none of the servers is an error, otherwise the first
+// server is returned.
+func (sel serverSelection) pick(servers openapi3.Servers, strict bool) (*openapi3.Server, error) {
+	if len(servers) == 0 {
+		return nil, nil
+	}
+	if sel.selector == "" {
+		return servers[0], nil
+	}
+	if i, err := strconv.Atoi(sel.selector); err == nil {
+		if i >= 0 && i < len(servers) {
+			return servers[i], nil
+		}
+	} else {
+		want := strings.ToLower(sel.selector)
+		for _, s := range servers {
+			if s != nil && strings.Contains(strings.ToLower(s.Description), want) {
+				return s, nil
+			}
+		}
+	}
+	if strict {
+		return nil, fmt.Errorf("no server %q among %d declared", sel.selector, len(servers))
+	}
+	return servers[0], nil
+}
+
+// resolve returns the URL of the selected server with its variables
+// substituted, resolved against the location of the spec. It returns an
+// empty string if there are no servers.
+func (sel serverSelection) resolve(servers openapi3.Servers, strict bool, specURL string) (string, error) {
+	s, err := sel.pick(servers, strict)
+	if s == nil || err != nil {
+		return "", err
+	}
+	u, err := sel.expand(s)
+	if err != nil {
+		return "", err
+	}
+	return resolveServerURL(u, specURL)
+}
+
+// expand substitutes the variables of the server URL template.
+func (sel serverSelection) expand(s *openapi3.Server) (string, error) {
+	var b strings.Builder
+	rest := s.URL
+	for {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		j := strings.IndexByte(rest[i:], '}')
+		if j < 0 {
+			return "", fmt.Errorf("server %s : unterminated variable", s.URL)
+		}
+		b.WriteString(rest[:i])
+		name := rest[i+1 : i+j]
+		v, err := sel.variable(s, name)
+		if err != nil {
+			return "", fmt.Errorf("server %s : %s", s.URL, err)
+		}
+		b.WriteString(v)
+		rest = rest[i+j+1:]
+	}
+}
+
+// variable returns the value of the named variable, checked against the
+// enum declared for it.
+func (sel serverSelection) variable(s *openapi3.Server, name string) (string, error) {
+	decl := s.Variables[name]
+	v, given := sel.vars[name]
+	if !given {
+		if decl == nil || decl.Default == nil {
+			return "", fmt.Errorf("no value for variable %q", name)
+		}
+		v = fmt.Sprint(decl.Default)
+	}
+	if decl == nil || len(decl.Enum) == 0 {
+		return v, nil
+	}
+	allowed := make([]string, len(decl.Enum))
+	for i, e := range decl.Enum {
+		allowed[i] = fmt.Sprint(e)
+		if allowed[i] == v {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("variable %q is %q, not one of %s", name, v, strings.Join(allowed, ", "))
+}
+
+// resolveServerURL resolves a relative server URL against the URL the spec
+// was read from. Specs read from files leave nothing to resolve against.
+func resolveServerURL(server, specURL string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q : %s", server, err)
+	}
+	if u.IsAbs() {
+		return server, nil
+	}
+	base, err := url.Parse(specURL)
+	if err != nil || !base.IsAbs() {
+		return "", fmt.Errorf("relative server URL %q needs a spec read from a URL", server)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// serverReference returns the URL relative servers are resolved against:
+// the location of the spec or, for specs read from files, the service URL.
+func (s Service) serverReference() string {
+	if u, err := url.Parse(s.specURL); err == nil && u.IsAbs() {
+		return s.specURL
+	}
+	return s.Info().URL + "/"
+}
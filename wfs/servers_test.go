@@ -0,0 +1,100 @@
+package wfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const serversSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "servers": [
+    {"url": "/v1", "description": "This server"},
+    {
+      "url": "https://{region}.example.com:{port}/wfs",
+      "description": "Regional production",
+      "variables": {
+        "region": {"default": "eu", "enum": ["eu", "us"]},
+        "port": {"default": "443"}
+      }
+    }
+  ],
+  "paths": {
+    "/collections": {
+      "get": {"operationId": "describeCollections", "responses": {"200": {"description": "ok"}}}
+    },
+    "/processes": {
+      "servers": [{"url": "https://processing.example.com/"}],
+      "get": {"operationId": "getProcesses", "responses": {"200": {"description": "ok"}}},
+      "post": {
+        "operationId": "execute",
+        "servers": [{"url": "https://{host}/"}],
+        "responses": {"200": {"description": "ok"}}
+      }
+    }
+  }
+}`
+
+func TestServers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(serversSpec))
+	}))
+	defer srv.Close()
+	cl := NewClient(srv.Client())
+	opURL := func(svc Service, id string) string {
+		op, err := svc.GetOperation(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return op.URL()
+	}
+
+	svc, err := cl.Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := svc.Info().URL; u != srv.URL+"/v1" {
+		t.Errorf("relative server %s", u)
+	}
+	if u := opURL(svc, "getProcesses"); u != "https://processing.example.com/processes" {
+		t.Errorf("path server %s", u)
+	}
+	op, _ := svc.GetOperation("execute")
+	if _, err := op.SimpleCall().buildRequest(); err == nil || !strings.Contains(err.Error(), `no value for variable "host"`) {
+		t.Errorf("expected variable error, got %v", err)
+	}
+
+	for _, sel := range []string{"1", "regional"} {
+		svc, err = cl.WithServer(sel).Connect(srv.URL, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := opURL(svc, "describeCollections"); u != "https://eu.example.com:443/wfs/collections" {
+			t.Errorf("server %s : %s", sel, u)
+		}
+	}
+	svc, err = cl.WithServer("1").WithServerVariables(map[string]string{"region": "us", "port": "8443", "host": "h"}).Connect(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := svc.Info().URL; u != "https://us.example.com:8443/wfs" {
+		t.Errorf("variables %s", u)
+	}
+	if u := opURL(svc, "execute"); u != "https://h/processes" {
+		t.Errorf("operation server %s", u)
+	}
+
+	if _, err := cl.WithServer("staging").Connect(srv.URL, false); err == nil {
+		t.Error("expected error for unknown server")
+	}
+	_, err = cl.WithServer("1").WithServerVariables(map[string]string{"region": "asia"}).Connect(srv.URL, false)
+	if err == nil || !strings.Contains(err.Error(), "not one of eu, us") {
+		t.Errorf("expected enum error, got %v", err)
+	}
+}
@@ -1,32 +1,414 @@
 package wfs
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/jban332/kin-openapi/openapi3"
 )
 
-// parseSpec attempts to parse and validate the provided specification.
-// if the components are missing, it patches some builtins in and then
-// attempts to validate
+// SpecError is returned when an OpenAPI document cannot be parsed.
+type SpecError struct {
+	// URL is the location of the document, empty if not known.
+	URL string
+	// Offset is the byte offset of a JSON syntax or type error, -1 if not
+	// known.
+	Offset int64
+	// Path is the dotted path of the offending member, empty if not known.
+	Path string
+	Err  error
+}
+
+func (e *SpecError) Error() string {
+	msg := "error parsing spec"
+	if e.URL != "" {
+		msg += " " + e.URL
+	}
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	if e.Offset >= 0 {
+		msg += fmt.Sprintf(" (offset %d)", e.Offset)
+	}
+	return msg + " : " + e.Err.Error()
+}
+
+// unmarshalProperty matches the property names in the nested errors of the
+// OpenAPI decoder.
+var unmarshalProperty = regexp.MustCompile(`property '([^']*)'`)
+
+// newSpecError wraps err with the offset and path it carries, if any.
+func newSpecError(err error) *SpecError {
+	e := &SpecError{Offset: -1, Err: err}
+	switch je := err.(type) {
+	case *json.SyntaxError:
+		e.Offset = je.Offset
+	case *json.UnmarshalTypeError:
+		e.Offset, e.Path = je.Offset, je.Field
+	default:
+		var names []string
+		for _, m := range unmarshalProperty.FindAllStringSubmatch(err.Error(), -1) {
+			names = append(names, m[1])
+		}
+		e.Path = strings.Join(names, ".")
+	}
+	return e
+}
+
+// Spec is an OpenAPI document loaded from a file or reader, to connect to a
+// service with ConnectSpec.
+type Spec struct {
+	doc     *openapi3.Swagger
+	source  string
+	patches PatchReport
+}
+
+// LoadSpec reads the JSON or YAML OpenAPI document at path. Undefined
+// components are patched as by a Client created with NewClient.
+func LoadSpec(path string) (*Spec, error) {
+	return Client{}.LoadSpec(path)
+}
+
+// ReadSpec works as per LoadSpec but reads the document from r.
+func ReadSpec(r io.Reader) (*Spec, error) {
+	return Client{}.ReadSpec(r)
+}
+
+// LoadSpec reads the JSON or YAML OpenAPI document at path, patching
+// undefined components as configured for the Client.
+func (c Client) LoadSpec(path string) (*Spec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	spec, err := c.readSpec(f, path)
+	if se, ok := err.(*SpecError); ok {
+		se.URL = path
+	}
+	return spec, err
+}
+
+// ReadSpec works as per LoadSpec but reads the document from r.
+func (c Client) ReadSpec(r io.Reader) (*Spec, error) {
+	return c.readSpec(r, "")
+}
+
+func (c Client) readSpec(r io.Reader, source string) (*Spec, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, report, err := c.componentPatch().parseSpec(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Patched) > 0 {
+		c.logger().Printf("spec %s references undefined components, patched %s", source, strings.Join(report.Patched, ", "))
+	}
+	return &Spec{doc, source, report}, nil
+}
+
+// parseSpec attempts to parse and validate the provided specification, given
+// as JSON or YAML, patching in the builtin components if needed.
 func parseSpec(data []byte) (*openapi3.Swagger, error) {
-	swag := &openapi3.Swagger{}
+	swag, _, err := builtinPatch.parseSpec(data)
+	return swag, err
+}
+
+// parseSpec attempts to parse and validate the provided specification, given
+// as JSON or YAML. Errors are of type *SpecError.
+// if referenced components are missing, it patches them in from the
+// fallbacks and then attempts to validate
+func (cp componentPatch) parseSpec(data []byte) (swag *openapi3.Swagger, report PatchReport, err error) {
+	data, err = specJSON(data)
+	if err != nil {
+		return nil, report, newSpecError(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, report, newSpecError(err)
+	}
+	report, err = cp.patchComponents(doc)
+	if err != nil {
+		return nil, report, &SpecError{Offset: -1, Path: "components", Err: err}
+	}
+	if len(report.Patched) > 0 {
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, report, newSpecError(err)
+		}
+	}
+	if path := nullEntry(doc); path != "" {
+		return nil, report, &SpecError{Offset: -1, Path: path, Err: fmt.Errorf("null entry")}
+	}
+	swag = &openapi3.Swagger{}
 	if err := swag.UnmarshalJSON(data); err != nil {
-		panic(err)
+		return nil, report, newSpecError(err)
+	}
+	if err := openapi3.NewSwaggerLoader().ResolveRefsIn(swag); err != nil {
+		return nil, report, newSpecError(err)
+	}
+	return swag, report, nil
+}
+
+// nullWalk finds the null entries of a document the OpenAPI loader
+// dereferences when resolving references: parameters, headers, request
+// bodies, responses, their media types and examples, and schemas.
+type nullWalk struct {
+	found string
+}
+
+// nullEntry returns the dotted path of the first null entry of doc, or "".
+func nullEntry(doc map[string]interface{}) string {
+	w := &nullWalk{}
+	components, _ := doc["components"].(map[string]interface{})
+	w.each("components.headers", components["headers"], w.parameter)
+	w.each("components.parameters", components["parameters"], w.parameter)
+	w.each("components.requestBodies", components["requestBodies"], w.requestBody)
+	w.each("components.responses", components["responses"], w.response)
+	w.each("components.schemas", components["schemas"], w.schema)
+	w.each("components.securitySchemes", components["securitySchemes"], nil)
+	w.each("components.examples", components["examples"], nil)
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, k := range sortedKeys(paths) {
+		item, _ := paths[k].(map[string]interface{})
+		path := "paths." + k
+		w.each(path+".parameters", item["parameters"], w.parameter)
+		for _, method := range []string{"connect", "delete", "get", "head", "options", "patch", "post", "put", "trace"} {
+			op, _ := item[method].(map[string]interface{})
+			w.each(path+"."+method+".parameters", op["parameters"], w.parameter)
+			if body, ok := op["requestBody"]; ok && body != nil {
+				w.requestBody(path+"."+method+".requestBody", body)
+			}
+			w.each(path+"."+method+".responses", op["responses"], w.response)
+		}
+	}
+	return w.found
+}
+
+// each calls fn, if any, with the path and value of the entries of the
+// object or array v, in order, stopping at the first null entry.
+func (w *nullWalk) each(path string, v interface{}, fn func(string, interface{})) {
+	visit := func(k string, e interface{}) {
+		switch {
+		case w.found != "":
+		case e == nil:
+			w.found = path + "." + k
+		case fn != nil:
+			fn(path+"."+k, e)
+		}
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			visit(k, v[k])
+		}
+	case []interface{}:
+		for i, e := range v {
+			visit(strconv.Itoa(i), e)
+		}
+	}
+}
+
+// parameter also serves headers, which have the same form.
+func (w *nullWalk) parameter(path string, v interface{}) {
+	p, _ := v.(map[string]interface{})
+	w.schema(path+".schema", p["schema"])
+}
+
+func (w *nullWalk) requestBody(path string, v interface{}) {
+	b, _ := v.(map[string]interface{})
+	w.each(path+".content", b["content"], func(path string, v interface{}) {
+		mt, _ := v.(map[string]interface{})
+		w.schema(path+".schema", mt["schema"])
+	})
+}
+
+// response differs from requestBody in that null media types are skipped
+// but their examples are resolved.
+func (w *nullWalk) response(path string, v interface{}) {
+	r, _ := v.(map[string]interface{})
+	content, _ := r["content"].(map[string]interface{})
+	for _, k := range sortedKeys(content) {
+		mt, _ := content[k].(map[string]interface{})
+		w.each(path+".content."+k+".examples", mt["examples"], nil)
+		w.schema(path+".content."+k+".schema", mt["schema"])
+	}
+}
+
+// schema walks the subschemas of v. A null schema is allowed where the
+// loader checks for it.
+func (w *nullWalk) schema(path string, v interface{}) {
+	s, _ := v.(map[string]interface{})
+	if w.found != "" || s == nil {
+		return
+	}
+	for _, k := range []string{"items", "additionalProperties", "not"} {
+		w.schema(path+"."+k, s[k])
 	}
-	loader := openapi3.NewSwaggerLoader()
-	if err := loader.ResolveRefsIn(swag); err != nil {
-		comps := openapi3.NewComponents()
-		if err := comps.UnmarshalJSON([]byte(_componentsJSON)); err != nil {
-			panic(err)
+	for _, k := range []string{"properties", "allOf", "anyOf", "oneOf"} {
+		w.each(path+"."+k, s[k], w.schema)
+	}
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// specJSON converts a YAML or JSON OpenAPI document to JSON the parser
+// understands. Documents must declare an OpenAPI 3.0 or 3.1 version; 3.1
+// schemas are rewritten to their closest 3.0 form.
+func specJSON(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("spec is neither JSON nor YAML : %s", err)
 		}
-		fmt.Println("WARNING: patching in std components", comps.Parameters)
-		swag.Components = comps
+		data = converted
 	}
-	if err := loader.ResolveRefsIn(swag); err != nil {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
 		return nil, err
 	}
-	return swag, nil
+	version, _ := doc["openapi"].(string)
+	switch {
+	case strings.HasPrefix(version, "3.0."):
+		return data, nil
+	case strings.HasPrefix(version, "3.1."):
+		if _, ok := doc["paths"]; !ok {
+			doc["paths"] = map[string]interface{}{}
+		}
+		delete(doc, "webhooks")
+		downgradeSpec(doc)
+		return json.Marshal(doc)
+	case version == "":
+		return nil, fmt.Errorf("not an OpenAPI document")
+	}
+	return nil, fmt.Errorf("unsupported OpenAPI version %s", version)
+}
+
+// downgradeSpec rewrites the schema objects of an OpenAPI 3.1 document, as
+// found in components, parameters, headers, request bodies and responses,
+// with downgradeSchema. Examples and other values are left alone.
+func downgradeSpec(doc map[string]interface{}) {
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		eachValue(components["schemas"], downgradeSchema)
+		eachValue(components["parameters"], downgradeParameter)
+		eachValue(components["headers"], downgradeParameter)
+		eachValue(components["requestBodies"], downgradeContent)
+		eachValue(components["responses"], downgradeResponse)
+		eachValue(components["callbacks"], downgradeCallback)
+		eachValue(components["pathItems"], downgradePathItem)
+	}
+	eachValue(doc["paths"], downgradePathItem)
+}
+
+// eachValue calls fn with the values of v if it is an object, or with its
+// elements if it is an array.
+func eachValue(v interface{}, fn func(interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, e := range v {
+			fn(e)
+		}
+	case []interface{}:
+		for _, e := range v {
+			fn(e)
+		}
+	}
+}
+
+func downgradePathItem(v interface{}) {
+	item, _ := v.(map[string]interface{})
+	eachValue(item["parameters"], downgradeParameter)
+	for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+		op, _ := item[method].(map[string]interface{})
+		eachValue(op["parameters"], downgradeParameter)
+		downgradeContent(op["requestBody"])
+		eachValue(op["responses"], downgradeResponse)
+		eachValue(op["callbacks"], downgradeCallback)
+	}
+}
+
+func downgradeCallback(v interface{}) {
+	eachValue(v, downgradePathItem)
+}
+
+// downgradeParameter also serves headers, which have the same form.
+func downgradeParameter(v interface{}) {
+	p, _ := v.(map[string]interface{})
+	downgradeSchema(p["schema"])
+	downgradeContent(p)
+}
+
+func downgradeResponse(v interface{}) {
+	r, _ := v.(map[string]interface{})
+	downgradeContent(r)
+	eachValue(r["headers"], downgradeParameter)
+}
+
+// downgradeContent rewrites the schemas of the media types of a request
+// body, response or parameter.
+func downgradeContent(v interface{}) {
+	m, _ := v.(map[string]interface{})
+	eachValue(m["content"], func(v interface{}) {
+		mt, _ := v.(map[string]interface{})
+		downgradeSchema(mt["schema"])
+	})
+}
+
+// downgradeSchema rewrites the JSON Schema 2020-12 forms used by OpenAPI 3.1
+// in the schema v and its subschemas: type arrays become a single type,
+// nullable if "null" was listed, numeric exclusive bounds become boolean
+// ones and const becomes a single value enum.
+func downgradeSchema(v interface{}) {
+	s, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if types, ok := s["type"].([]interface{}); ok {
+		delete(s, "type")
+		for _, t := range types {
+			if t == "null" {
+				s["nullable"] = true
+			} else if _, set := s["type"]; !set {
+				s["type"] = t
+			}
+		}
+	}
+	for _, bound := range []string{"Minimum", "Maximum"} {
+		if n, ok := s["exclusive"+bound].(float64); ok {
+			s[strings.ToLower(bound)] = n
+			s["exclusive"+bound] = true
+		}
+	}
+	if c, ok := s["const"]; ok {
+		delete(s, "const")
+		s["enum"] = []interface{}{c}
+	}
+	for _, k := range []string{"items", "additionalProperties", "not", "if", "then", "else", "contains",
+		"propertyNames", "unevaluatedItems", "unevaluatedProperties", "contentSchema"} {
+		downgradeSchema(s[k])
+	}
+	for _, k := range []string{"properties", "patternProperties", "dependentSchemas", "$defs", "definitions",
+		"allOf", "anyOf", "oneOf", "prefixItems"} {
+		eachValue(s[k], downgradeSchema)
+	}
 }
 
 const _componentsJSON = `
@@ -64,16 +446,14 @@ const _componentsJSON = `
       "properties" : {
         "crs" : {
           "type" : "string",
-          "enum" : [ "http://www.opengis.net/def/crs/OGC/1.3/CRS84" ],
+          "format" : "uri",
           "default" : "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
         },
         "bbox" : {
           "type" : "array",
-          "description" : "minimum longitude, minimum latitude, maximum longitude, maximum latitude",
+          "description" : "minimum longitude, minimum latitude, maximum longitude, maximum latitude (or the equivalent in the axis order of crs)",
           "example" : [ -180, -90, 180, 90 ],
           "items" : {
-            "maximum" : 180,
-            "minimum" : -180,
             "maxItems" : 4,
             "minItems" : 4,
             "type" : "number"
@@ -329,8 +709,6 @@ const _componentsJSON = `
       "schema" : {
         "type" : "array",
         "items" : {
-          "maximum" : 180,
-          "minimum" : -180,
           "maxItems" : 4,
           "minItems" : 4,
           "type" : "number"
@@ -351,6 +729,30 @@ const _componentsJSON = `
       },
       "example" : "results"
     },
+    "crs" : {
+      "name" : "crs",
+      "in" : "query",
+      "description" : "The coordinate reference system of the geometries in the response. Must be one of the CRS supported by the collection.",
+      "required" : false,
+      "style" : "form",
+      "explode" : false,
+      "schema" : {
+        "type" : "string",
+        "format" : "uri"
+      }
+    },
+    "bbox-crs" : {
+      "name" : "bbox-crs",
+      "in" : "query",
+      "description" : "The coordinate reference system of the bbox parameter. Defaults to CRS84.",
+      "required" : false,
+      "style" : "form",
+      "explode" : false,
+      "schema" : {
+        "type" : "string",
+        "format" : "uri"
+      }
+    },
     "id" : {
       "name" : "id",
       "in" : "path",
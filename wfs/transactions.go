@@ -0,0 +1,158 @@
+package wfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// MergePatchType is the media type of JSON merge patches (RFC 7396).
+const MergePatchType = "application/merge-patch+json"
+
+// ItemRef identifies a feature written with Create or Replace.
+type ItemRef struct {
+	ID string
+	// Location is the URL of the feature as returned by the server.
+	Location string
+	// ETag is the entity tag of the new version, if the server reports one.
+	ETag string
+}
+
+// IsPreconditionFailed reports whether err is the response to a write whose
+// If-Match ETag did not match the current version of the feature.
+func IsPreconditionFailed(err error) bool {
+	se, ok := err.(*StatusError)
+	return ok && se.StatusCode == http.StatusPreconditionFailed
+}
+
+// Create adds f to the collection as defined by OGC API - Features - Part 4.
+// The ID assigned by the server is taken from the Location header.
+func (c Collection) Create(ctx context.Context, f Feature) (ItemRef, error) {
+	if f.Type == "" {
+		f.Type = "Feature"
+	}
+	body, err := json.Marshal(f)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	u, err := c.svc.paths.collectionItems(c.Name)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	header, err := c.svc.cl.write(ctx, "POST", u, MediaTypes.LookupShort("geojson").Full, body, "")
+	if err != nil {
+		return ItemRef{}, err
+	}
+	ref := ItemRef{Location: header.Get("Location"), ETag: header.Get("ETag")}
+	if ref.Location == "" {
+		return ref, fmt.Errorf("no Location returned creating a feature in %s", c.Name)
+	}
+	ref.Location, ref.ID, err = parseLocation(u, ref.Location)
+	return ref, err
+}
+
+// Replace replaces the feature fid with f. If etag is not empty the request
+// is conditional on it matching the current version of the feature.
+func (c Collection) Replace(ctx context.Context, fid string, f Feature, etag string) (ItemRef, error) {
+	if f.Type == "" {
+		f.Type = "Feature"
+	}
+	body, err := json.Marshal(f)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	u, err := c.svc.paths.collectionItem(c.Name, fid)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	header, err := c.svc.cl.write(ctx, "PUT", u, MediaTypes.LookupShort("geojson").Full, body, etag)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	return ItemRef{ID: fid, Location: u, ETag: header.Get("ETag")}, nil
+}
+
+// Update modifies the feature fid with a JSON merge patch, a partial feature
+// whose members replace those of the feature; null values remove members.
+// If etag is not empty the request is conditional on it matching the current
+// version of the feature.
+func (c Collection) Update(ctx context.Context, fid string, patch interface{}, etag string) (ItemRef, error) {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	u, err := c.svc.paths.collectionItem(c.Name, fid)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	header, err := c.svc.cl.write(ctx, "PATCH", u, MergePatchType, body, etag)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	return ItemRef{ID: fid, Location: u, ETag: header.Get("ETag")}, nil
+}
+
+// Delete removes the feature fid. If etag is not empty the request is
+// conditional on it matching the current version of the feature.
+func (c Collection) Delete(ctx context.Context, fid, etag string) error {
+	u, err := c.svc.paths.collectionItem(c.Name, fid)
+	if err != nil {
+		return err
+	}
+	_, err = c.svc.cl.write(ctx, "DELETE", u, "", nil, etag)
+	return err
+}
+
+// parseLocation resolves a Location header against the request URL and
+// returns the URL together with the feature ID, its last path segment.
+func parseLocation(base, location string) (string, string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", "", err
+	}
+	l, err := url.Parse(location)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Location %q : %s", location, err)
+	}
+	abs := b.ResolveReference(l)
+	segment := path.Base(strings.TrimSuffix(abs.EscapedPath(), "/"))
+	id, err := url.PathUnescape(segment)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Location %q : %s", location, err)
+	}
+	return abs.String(), id, nil
+}
+
+// write performs a request with a body, returning the response headers.
+// Any 2xx status is a success. ifMatch, if not empty, is sent as the If-Match
+// header so the server rejects the write if the feature has changed.
+func (c Client) write(ctx context.Context, method, u, contentType string, body []byte, ifMatch string) (http.Header, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling %s : %s", req.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{req.URL.String(), resp.StatusCode, resp.Status}
+	}
+	return resp.Header, nil
+}
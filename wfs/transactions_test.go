@@ -0,0 +1,157 @@
+package wfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// featureStore is a minimal Part 4 server keeping features in memory. The
+// ETag of a feature is its version number.
+type featureStore struct {
+	mu       sync.Mutex
+	features map[string]map[string]interface{}
+	versions map[string]int
+	next     int
+}
+
+func (s *featureStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefix := "/collections/lakes/items/"
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	id, _ := url.PathUnescape(strings.TrimSuffix(path[len(prefix):], "/"))
+	if id == "" {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/geo+json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var f map[string]interface{}
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &f)
+		s.next++
+		id = fmt.Sprintf("new %d", s.next)
+		s.features[id] = f
+		s.versions[id] = 1
+		w.Header().Set("Location", "/collections/lakes/items/"+url.PathEscape(id))
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	f, ok := s.features[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	etag := fmt.Sprintf(`"%d"`, s.versions[id])
+	if m := r.Header.Get("If-Match"); m != "" && m != etag {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		w.Header().Set("ETag", etag)
+		json.NewEncoder(w).Encode(f)
+		return
+	case "PUT":
+		var nf map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&nf)
+		s.features[id] = nf
+	case "PATCH":
+		if r.Header.Get("Content-Type") != MergePatchType {
+			http.Error(w, "bad request", http.StatusUnsupportedMediaType)
+			return
+		}
+		var patch map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&patch)
+		props := f["properties"].(map[string]interface{})
+		for k, v := range patch["properties"].(map[string]interface{}) {
+			if v == nil {
+				delete(props, k)
+			} else {
+				props[k] = v
+			}
+		}
+	case "DELETE":
+		delete(s.features, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.versions[id]++
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, s.versions[id]))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestTransactions(t *testing.T) {
+	store := &featureStore{features: map[string]map[string]interface{}{}, versions: map[string]int{}}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	coll := testService(srv, nil).Collection("lakes")
+	ctx := context.Background()
+
+	ref, err := coll.Create(ctx, Feature{
+		Geometry:   &Geometry{Type: "Point", Point: Position{1, 2}},
+		Properties: map[string]interface{}{"name": "a", "depth": 3.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.ID != "new 1" || ref.Location != srv.URL+"/collections/lakes/items/new%201" || ref.ETag != `"1"` {
+		t.Errorf("created %+v", ref)
+	}
+
+	ref, err = coll.Update(ctx, ref.ID, map[string]interface{}{
+		"properties": map[string]interface{}{"name": "b", "depth": nil},
+	}, ref.ETag)
+	if err != nil || ref.ETag != `"2"` {
+		t.Fatalf("update %+v %v", ref, err)
+	}
+	f, etag, err := coll.ItemForUpdate(ctx, ref.ID)
+	if err != nil || etag != `"2"` {
+		t.Fatalf("get %s %v", etag, err)
+	}
+	if len(f.Properties) != 1 || f.Properties["name"] != "b" {
+		t.Errorf("patched properties %v", f.Properties)
+	}
+
+	f.Properties["name"] = "c"
+	if _, err := coll.Replace(ctx, ref.ID, f, `"1"`); !IsPreconditionFailed(err) {
+		t.Errorf("expected precondition failed, got %v", err)
+	}
+	if ref, err = coll.Replace(ctx, ref.ID, f, etag); err != nil || ref.ETag != `"3"` {
+		t.Errorf("replace %+v %v", ref, err)
+	}
+	if err := coll.Delete(ctx, ref.ID, ref.ETag); err != nil {
+		t.Error(err)
+	}
+	if _, err := coll.Item(ctx, ref.ID); !isNotFound(err) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	cases := []struct {
+		location, url, id string
+	}{
+		{"/collections/c/items/42", "http://h/collections/c/items/42", "42"},
+		{"http://other/items/a%2Fb/", "http://other/items/a%2Fb/", "a/b"},
+		{"x", "http://h/collections/c/items/x", "x"},
+	}
+	for _, c := range cases {
+		u, id, err := parseLocation("http://h/collections/c/items/", c.location)
+		if err != nil || u != c.url || id != c.id {
+			t.Errorf("%s: %s %s %v", c.location, u, id, err)
+		}
+	}
+}
@@ -1,10 +1,34 @@
 package wfs
 
-// MediaType represents supported encodings.
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Decoder decodes a response body into v.
+type Decoder func(data []byte, v interface{}) error
+
+// MediaType represents supported encodings. Full may carry parameters such
+// as "version=3.2"; they are ignored when matching types.
 type MediaType struct {
-	p     interface{}
 	Short string
 	Full  string
+	// Decode decodes responses of this type, nil if they can only be written
+	// out as is.
+	Decode Decoder
+}
+
+// base returns the type without parameters, in lower case.
+func (m MediaType) base() string {
+	return mediaBase(m.Full)
+}
+
+func mediaBase(t string) string {
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(t, ";", 2)[0]))
 }
 
 type mediaTypes []MediaType
@@ -13,7 +37,7 @@ type mediaTypes []MediaType
 // will be returned.
 func (m mediaTypes) Lookup(v string) MediaType {
 	for _, t := range m {
-		if v == t.Short || v == t.Full {
+		if v == t.Short || mediaBase(v) == t.base() {
 			return t
 		}
 	}
@@ -30,11 +54,133 @@ func (m mediaTypes) LookupShort(v string) MediaType {
 	return MediaType{}
 }
 
-// MediaTypes is the collection of supported MediaType.
+func decodeJSON(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// decodeGML decodes feature collections with DecodeGML and anything else as
+// plain XML.
+func decodeGML(data []byte, v interface{}) error {
+	if fc, ok := v.(*FeatureCollection); ok {
+		decoded, err := DecodeGML(strings.NewReader(string(data)))
+		if err != nil {
+			return err
+		}
+		*fc = decoded
+		return nil
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// MediaTypes is the collection of supported MediaType. Use RegisterMediaType
+// to add to it.
 var MediaTypes = mediaTypes{
-	MediaType{nil, "json", "application/json"},
-	MediaType{nil, "geojson", "application/geo+json"},
-	MediaType{nil, "html", "text/html"},
-	MediaType{nil, "xml", "application/xml"},
-	MediaType{nil, "ldjson", "application/ld+json"},
+	{"json", "application/json", decodeJSON},
+	{"geojson", "application/geo+json", decodeJSON},
+	{"html", "text/html", nil},
+	{"xml", "application/xml", decodeGML},
+	{"gml", "application/gml+xml; version=3.2", decodeGML},
+	{"ldjson", "application/ld+json", decodeJSON},
+}
+
+// RegisterMediaType adds mt to MediaTypes, replacing any MediaType with the
+// same short name. It is not safe for concurrent use with requests and is
+// meant to be called during initialization.
+func RegisterMediaType(mt MediaType) {
+	for i, t := range MediaTypes {
+		if t.Short == mt.Short {
+			MediaTypes[i] = mt
+			return
+		}
+	}
+	MediaTypes = append(MediaTypes, mt)
+}
+
+// decode decodes data of the given Content-Type into v using the decoder of
+// the registered MediaType.
+func decode(data []byte, contentType string, v interface{}) error {
+	mt := MediaTypes.Lookup(contentType)
+	if mt.Decode == nil {
+		// servers often omit the Content-Type or send a generic one
+		if strings.HasPrefix(strings.TrimSpace(string(data)), "<") {
+			return decodeGML(data, v)
+		}
+		return decodeJSON(data, v)
+	}
+	return mt.Decode(data, v)
+}
+
+// acceptHeader builds an Accept header preferring the media types in the
+// given order: the first has the implicit quality 1, the following ones
+// decreasing qualities down to 0.1.
+func acceptHeader(types []MediaType) string {
+	parts := make([]string, 0, len(types))
+	for i, t := range types {
+		q := 1 - 0.1*float64(i)
+		if q < 0.1 {
+			q = 0.1
+		}
+		if i == 0 {
+			parts = append(parts, t.Full)
+			continue
+		}
+		parts = append(parts, t.Full+";q="+strconv.FormatFloat(q, 'f', 1, 64))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchesContentType reports whether a response Content-Type is one of the
+// accepted types. An empty Content-Type is accepted.
+func matchesContentType(contentType string, accepted []MediaType) bool {
+	if contentType == "" {
+		return true
+	}
+	base := mediaBase(contentType)
+	for _, t := range accepted {
+		if t.base() == base {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentTypeError is returned when a response does not have one of the
+// requested media types.
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+	Accept      string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q from %s, accepted %s", e.ContentType, e.URL, e.Accept)
+}
+
+// responseMediaTypes returns the media types declared by the success
+// responses of an operation. Registered types come first in the order of
+// MediaTypes, followed by unknown ones.
+func responseMediaTypes(content map[string]bool) []MediaType {
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rank := func(t MediaType) int {
+		for i, r := range MediaTypes {
+			if r.base() == t.base() {
+				return i
+			}
+		}
+		return len(MediaTypes)
+	}
+	types := make([]MediaType, 0, len(keys))
+	for _, k := range keys {
+		mt := MediaTypes.Lookup(k)
+		if mt.Full == "" {
+			mt = MediaType{Full: k}
+		}
+		types = append(types, mt)
+	}
+	sort.SliceStable(types, func(i, j int) bool { return rank(types[i]) < rank(types[j]) })
+	return types
 }
@@ -0,0 +1,119 @@
+package wfs
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const negotiationSpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "test", "version": "1"},
+  "servers": [{"url": "http://example.com"}],
+  "paths": {
+    "/collections/{collectionId}/items": {
+      "get": {
+        "operationId": "getFeatures",
+        "parameters": [
+          {"name": "collectionId", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "f", "in": "query", "schema": {"type": "string", "enum": ["json", "html"]}}
+        ],
+        "responses": {
+          "200": {
+            "description": "features",
+            "content": {
+              "text/html": {},
+              "application/vnd.custom": {},
+              "application/geo+json": {}
+            }
+          },
+          "400": {"description": "error", "content": {"application/problem+json": {}}}
+        }
+      }
+    }
+  }
+}`
+
+func negotiationOperation(t *testing.T) Operation {
+	spec, err := parseSpec([]byte(negotiationSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("http://example.com/")
+	svc := Service{spec: spec, paths: pather{u, newStylePaths}, server: "http://example.com"}
+	op, err := svc.GetOperation("getFeatures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return op
+}
+
+func TestOperationMediaTypes(t *testing.T) {
+	op := negotiationOperation(t)
+	var names []string
+	for _, mt := range op.MediaTypes() {
+		names = append(names, mt.Full)
+	}
+	if got := strings.Join(names, ","); got != "application/geo+json,text/html,application/vnd.custom" {
+		t.Errorf("media types %s", got)
+	}
+}
+
+func TestCallNegotiation(t *testing.T) {
+	op := negotiationOperation(t)
+	call, err := op.Call(map[string]interface{}{"collectionId": "lakes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _, err := call.request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := req.Header.Get("Accept"); a != "application/geo+json, text/html;q=0.9, application/vnd.custom;q=0.8" {
+		t.Errorf("accept %s", a)
+	}
+	// geojson is not in the enum of f
+	if req.URL.String() != "http://example.com/collections/lakes/items" {
+		t.Errorf("url %s", req.URL)
+	}
+
+	req, _, err = call.Accept("html").request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.RawQuery != "f=html" || req.Header.Get("Accept") != "text/html" {
+		t.Errorf("html %s %s", req.URL, req.Header.Get("Accept"))
+	}
+
+	if _, _, err := call.Accept("json").request(); err == nil {
+		t.Error("expected error for undeclared media type")
+	}
+	if _, _, err := call.Accept("application/vnd.custom").request(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMediaTypes(t *testing.T) {
+	if mt := MediaTypes.Lookup("application/gml+xml; version=3.2; subtype=x"); mt.Short != "gml" {
+		t.Errorf("lookup %v", mt)
+	}
+	if !matchesContentType("application/geo+json; charset=utf-8", []MediaType{MediaTypes.LookupShort("geojson")}) {
+		t.Error("content type with parameters")
+	}
+	if matchesContentType("text/html", []MediaType{MediaTypes.LookupShort("geojson")}) {
+		t.Error("mismatched content type")
+	}
+
+	saved := MediaTypes
+	defer func() { MediaTypes = saved }()
+	MediaTypes = append(mediaTypes(nil), saved...)
+	called := false
+	RegisterMediaType(MediaType{"csv", "text/csv", func([]byte, interface{}) error {
+		called = true
+		return nil
+	}})
+	if err := decode([]byte("a,b"), "text/csv; header=present", nil); err != nil || !called {
+		t.Errorf("custom decoder %v %v", called, err)
+	}
+}
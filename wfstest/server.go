@@ -0,0 +1,443 @@
+// Package wfstest serves feature collections as a WFS3 service for testing
+// code built on the wfs package without a live endpoint.
+//
+// Collections are served in the old or new path layout of the wfs package
+// along with a matching OpenAPI document. Items requests support bbox,
+// limit, startIndex and datetime and return next links for paging. Latency
+// and errors can be injected to test the resilience of clients.
+package wfstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// Collection is a feature collection served by the mock service.
+type Collection struct {
+	Name        string
+	Title       string
+	Description string
+	Features    []wfs.Feature
+	// TimeProperty is the feature property datetime queries select on,
+	// "datetime" if empty. Its values are RFC 3339 timestamps or dates.
+	TimeProperty string
+}
+
+// LoadCollection reads a collection from a GeoJSON FeatureCollection file.
+// The collection is named after the file, without its extension.
+func LoadCollection(path string) (Collection, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Collection{}, err
+	}
+	var fc wfs.FeatureCollection
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return Collection{}, fmt.Errorf("error reading %s : %s", path, err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return Collection{Name: name, Features: fc.Features}, nil
+}
+
+// Config configures the mock service.
+type Config struct {
+	Collections []Collection
+	// OldStyle serves the collections at /<collection> rather than at
+	// /collections/<collection>/items.
+	OldStyle bool
+	// Latency delays every response.
+	Latency time.Duration
+	// ErrorRate is the fraction of requests, from 0 to 1, answered with
+	// ErrorStatus instead.
+	ErrorRate float64
+	// ErrorStatus is the status of injected errors, 503 if zero.
+	ErrorStatus int
+	// DefaultLimit is the page size if none is requested, 10 if zero.
+	DefaultLimit int
+}
+
+// NewServer starts a server for the service configured by cfg. The caller
+// closes it when done.
+func NewServer(cfg Config) *httptest.Server {
+	return httptest.NewServer(Handler(cfg))
+}
+
+// Handler returns a handler serving the service configured by cfg at the
+// root path.
+func Handler(cfg Config) http.Handler {
+	s := &service{cfg: cfg, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	if s.cfg.ErrorStatus == 0 {
+		s.cfg.ErrorStatus = http.StatusServiceUnavailable
+	}
+	if s.cfg.DefaultLimit <= 0 {
+		s.cfg.DefaultLimit = 10
+	}
+	return s
+}
+
+type service struct {
+	cfg Config
+	// rand is shared between requests and guarded by mu
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (s *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Latency > 0 {
+		select {
+		case <-time.After(s.cfg.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if s.cfg.ErrorRate > 0 {
+		s.mu.Lock()
+		fail := s.rand.Float64() < s.cfg.ErrorRate
+		s.mu.Unlock()
+		if fail {
+			writeError(w, s.cfg.ErrorStatus, "injected error")
+			return
+		}
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	// the wfs package requests paths with a trailing slash
+	var segments []string
+	for _, seg := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		seg, err := url.PathUnescape(seg)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	if len(segments) == 1 && segments[0] == "api" {
+		s.serveSpec(w, r)
+		return
+	}
+	if s.cfg.OldStyle {
+		s.routeOld(w, r, segments)
+	} else {
+		s.routeNew(w, r, segments)
+	}
+}
+
+// routeOld serves / -> collections, /<collection> -> items and
+// /<collection>/<fid> -> item.
+func (s *service) routeOld(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch len(segments) {
+	case 0:
+		s.serveCollections(w, r)
+		return
+	case 1, 2:
+		c, ok := s.collection(segments[0])
+		if !ok {
+			break
+		}
+		if len(segments) == 1 {
+			s.serveItems(w, r, c)
+		} else {
+			s.serveItem(w, c, segments[1])
+		}
+		return
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+// routeNew serves the landing page, conformance, collections and
+// /collections/<collection>[/items[/<fid>]].
+func (s *service) routeNew(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0:
+		base := baseURL(r)
+		writeJSON(w, "application/json", map[string]interface{}{
+			"title": "wfstest",
+			"links": []wfs.Link{
+				{Href: base + "/", Rel: "self", Type: "application/json"},
+				{Href: base + "/api", Rel: "service-desc", Type: specType},
+				{Href: base + "/conformance", Rel: "conformance", Type: "application/json"},
+				{Href: base + "/collections", Rel: "data", Type: "application/json"},
+			},
+		})
+		return
+	case len(segments) == 1 && segments[0] == "conformance":
+		writeJSON(w, "application/json", map[string]interface{}{"conformsTo": conformance})
+		return
+	case len(segments) == 1 && segments[0] == "collections":
+		s.serveCollections(w, r)
+		return
+	case len(segments) >= 2 && len(segments) <= 4 && segments[0] == "collections":
+		c, ok := s.collection(segments[1])
+		if !ok {
+			break
+		}
+		switch {
+		case len(segments) == 2:
+			writeJSON(w, "application/json", s.info(r, c))
+			return
+		case segments[2] != "items":
+		case len(segments) == 3:
+			s.serveItems(w, r, c)
+			return
+		default:
+			s.serveItem(w, c, segments[3])
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+var conformance = []string{
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/oas30",
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/geojson",
+}
+
+func (s *service) collection(name string) (Collection, bool) {
+	for _, c := range s.cfg.Collections {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Collection{}, false
+}
+
+// itemsURL returns the URL of the items of c.
+func (s *service) itemsURL(r *http.Request, c Collection) string {
+	if s.cfg.OldStyle {
+		return baseURL(r) + "/" + url.PathEscape(c.Name)
+	}
+	return baseURL(r) + "/collections/" + url.PathEscape(c.Name) + "/items"
+}
+
+func (s *service) info(r *http.Request, c Collection) wfs.CollectionInfo {
+	info := wfs.CollectionInfo{
+		Name:        c.Name,
+		Title:       c.Title,
+		Description: c.Description,
+		Links:       []wfs.Link{{Href: s.itemsURL(r, c), Rel: "items", Type: "application/geo+json"}},
+	}
+	first := true
+	for _, f := range c.Features {
+		b, ok := f.Geometry.Bounds()
+		if !ok {
+			continue
+		}
+		if first {
+			info.Extent.BBox = b
+			first = false
+			continue
+		}
+		e := &info.Extent.BBox
+		e[0], e[1] = math.Min(e[0], b[0]), math.Min(e[1], b[1])
+		e[2], e[3] = math.Max(e[2], b[2]), math.Max(e[3], b[3])
+	}
+	return info
+}
+
+func (s *service) serveCollections(w http.ResponseWriter, r *http.Request) {
+	infos := []wfs.CollectionInfo{}
+	for _, c := range s.cfg.Collections {
+		infos = append(infos, s.info(r, c))
+	}
+	writeJSON(w, "application/json", map[string]interface{}{"collections": infos})
+}
+
+func (s *service) serveItems(w http.ResponseWriter, r *http.Request, c Collection) {
+	q := r.URL.Query()
+	limit, start := s.cfg.DefaultLimit, 0
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+	}
+	if v := q.Get("startIndex"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil || start < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid startIndex %q", v))
+			return
+		}
+	}
+	var bbox *wfs.BBox
+	if v := q.Get("bbox"); v != "" {
+		b, err := wfs.ParseBBox(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		bbox = &b
+	}
+	var during *interval
+	if v := q.Get("datetime"); v != "" {
+		iv, err := parseInterval(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		during = &iv
+	}
+	timeProp := c.TimeProperty
+	if timeProp == "" {
+		timeProp = "datetime"
+	}
+	matched := []wfs.Feature{}
+	for _, f := range c.Features {
+		if bbox != nil && !intersects(f, *bbox) {
+			continue
+		}
+		if during != nil && !during.contains(f.Properties[timeProp]) {
+			continue
+		}
+		matched = append(matched, f)
+	}
+	if limit > len(matched) {
+		// keeps start+limit from overflowing
+		limit = len(matched)
+	}
+	page := []wfs.Feature{}
+	if start < len(matched) {
+		end := start + limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page = matched[start:end]
+	}
+	self := s.itemsURL(r, c)
+	href := self
+	if r.URL.RawQuery != "" {
+		href += "?" + r.URL.RawQuery
+	}
+	links := []wfs.Link{{Href: href, Rel: "self", Type: "application/geo+json"}}
+	if start < len(matched)-limit {
+		q.Set("startIndex", strconv.Itoa(start+limit))
+		q.Set("limit", strconv.Itoa(limit))
+		links = append(links, wfs.Link{Href: self + "?" + q.Encode(), Rel: "next", Type: "application/geo+json"})
+	}
+	writeJSON(w, "application/geo+json", wfs.FeatureCollection{
+		Type:           "FeatureCollection",
+		Features:       page,
+		Links:          links,
+		NumberMatched:  len(matched),
+		NumberReturned: len(page),
+	})
+}
+
+func (s *service) serveItem(w http.ResponseWriter, c Collection, fid string) {
+	for _, f := range c.Features {
+		if f.ID != nil && fmt.Sprint(f.ID) == fid {
+			writeJSON(w, "application/geo+json", f)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("no feature %q in %s", fid, c.Name))
+}
+
+func intersects(f wfs.Feature, b wfs.BBox) bool {
+	fb, ok := f.Geometry.Bounds()
+	return ok && fb[0] <= b[2] && fb[2] >= b[0] && fb[1] <= b[3] && fb[3] >= b[1]
+}
+
+// interval is a datetime query, open ended where a bound is zero.
+type interval struct {
+	start, end time.Time
+}
+
+// parseInterval parses an instant or an interval of two instants separated
+// by "/", where ".." or an empty instant leaves the interval open. A date
+// without a time covers the whole day.
+func parseInterval(s string) (interval, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) > 2 {
+		return interval{}, fmt.Errorf("invalid datetime %q", s)
+	}
+	var iv interval
+	for i, p := range parts {
+		if p == ".." || p == "" {
+			continue
+		}
+		t, day, err := parseTime(p)
+		if err != nil {
+			return interval{}, fmt.Errorf("invalid datetime %q", s)
+		}
+		if i == 0 {
+			iv.start = t
+		}
+		if i == len(parts)-1 {
+			iv.end = t
+			if day {
+				iv.end = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		}
+	}
+	return iv, nil
+}
+
+// parseTime parses an RFC 3339 time or a date, reporting which it was.
+func parseTime(s string) (t time.Time, day bool, err error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse("2006-01-02", s)
+	return t, true, err
+}
+
+// contains reports whether the property value v is a time within the
+// interval, bounds included.
+func (iv interval) contains(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	t, _, err := parseTime(s)
+	if err != nil {
+		return false
+	}
+	return (iv.start.IsZero() || !t.Before(iv.start)) && (iv.end.IsZero() || !t.After(iv.end))
+}
+
+// baseURL returns the scheme and host the request was made to.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func writeJSON(w http.ResponseWriter, contentType string, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"title":  http.StatusText(status),
+		"status": status,
+		"detail": detail,
+	})
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,164 @@
+package wfstest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ischneider/go-wfs-client/wfs"
+)
+
+// roads has 25 point features along x = 0..24 with a daily datetime.
+func roads() Collection {
+	c := Collection{Name: "roads", Title: "Roads"}
+	for i := 0; i < 25; i++ {
+		c.Features = append(c.Features, wfs.Feature{
+			Type:       "Feature",
+			ID:         fmt.Sprintf("r/%d", i),
+			Geometry:   &wfs.Geometry{Type: "Point", Point: wfs.Position{float64(i), 1}},
+			Properties: map[string]interface{}{"datetime": fmt.Sprintf("2018-01-%02dT12:00:00Z", i+1)},
+		})
+	}
+	return c
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	for _, oldStyle := range []bool{false, true} {
+		srv := NewServer(Config{Collections: []Collection{roads()}, OldStyle: oldStyle})
+		defer srv.Close()
+		svc, err := wfs.NewClient(srv.Client()).Connect(srv.URL, oldStyle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.GetOperation("getFeatures"); err != nil {
+			t.Error(err)
+		}
+		colls, err := svc.Collections(ctx)
+		if err != nil || len(colls) != 1 || colls[0].Extent.BBox != (wfs.BBox{0, 1, 24, 1}) {
+			t.Errorf("collections %+v %v", colls, err)
+		}
+
+		c := svc.Collection("roads")
+		count, pages := 0, c.Pages(ctx, wfs.ItemsQuery{Limit: 10})
+		for pages.Next() {
+			count += len(pages.Page().Features)
+		}
+		if pages.Err() != nil || count != 25 {
+			t.Errorf("old style %v paged %d features, %v", oldStyle, count, pages.Err())
+		}
+
+		fc, err := c.Items(ctx, wfs.ItemsQuery{BBox: &wfs.BBox{2.5, 0, 4.5, 2}, Datetime: "2018-01-05T00:00:00Z/.."})
+		if err != nil || fc.NumberMatched != 1 || len(fc.Features) != 1 || fc.Features[0].ID != "r/4" {
+			t.Errorf("filtered %+v %v", fc, err)
+		}
+		f, err := c.Item(ctx, "r/7")
+		if err != nil || f.ID != "r/7" {
+			t.Errorf("item %+v %v", f, err)
+		}
+		if _, err := c.Item(ctx, "missing"); err == nil {
+			t.Error("expected error for missing feature")
+		}
+	}
+}
+
+func TestServerQueries(t *testing.T) {
+	srv := NewServer(Config{Collections: []Collection{roads()}, DefaultLimit: 5})
+	defer srv.Close()
+	get := func(query string) (int, string) {
+		resp, err := http.Get(srv.URL + "/collections/roads/items?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	if _, body := get(""); !strings.Contains(body, `"numberReturned":5`) || !strings.Contains(body, "startIndex=5") {
+		t.Errorf("default page %s", body)
+	}
+	if _, body := get("startIndex=20&limit=10"); !strings.Contains(body, `"numberReturned":5`) || strings.Contains(body, `"next"`) {
+		t.Errorf("last page %s", body)
+	}
+	if _, body := get("datetime=2018-01-03"); !strings.Contains(body, `"numberMatched":1`) {
+		t.Errorf("date %s", body)
+	}
+	if _, body := get("datetime=2018-01-03T00:00:00Z"); !strings.Contains(body, `"features":[]`) {
+		t.Errorf("instant %s", body)
+	}
+	if _, body := get("datetime=2018-01-02/2018-01-03"); !strings.Contains(body, `"numberMatched":2`) {
+		t.Errorf("date interval %s", body)
+	}
+	if status, body := get("limit=9223372036854775807&startIndex=1"); status != http.StatusOK || !strings.Contains(body, `"numberReturned":24`) || strings.Contains(body, `"next"`) {
+		t.Errorf("large limit %d %s", status, body)
+	}
+	if _, body := get("startIndex=9223372036854775807"); !strings.Contains(body, `"features":[]`) || strings.Contains(body, `"next"`) {
+		t.Errorf("large startIndex %s", body)
+	}
+	resp, err := http.Get(srv.URL + "/collections/roads/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc wfs.FeatureCollection
+	json.NewDecoder(resp.Body).Decode(&fc)
+	resp.Body.Close()
+	if len(fc.Links) == 0 || strings.HasSuffix(fc.Links[0].Href, "?") {
+		t.Errorf("self link %v", fc.Links)
+	}
+	if _, body := get("datetime=../2018-01-03T12:00:00Z"); !strings.Contains(body, `"numberMatched":3`) {
+		t.Errorf("open interval %s", body)
+	}
+	for _, q := range []string{"limit=0", "startIndex=x", "bbox=1,2", "datetime=yesterday"} {
+		if status, _ := get(q); status != http.StatusBadRequest {
+			t.Errorf("%s status %d", q, status)
+		}
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	srv := NewServer(Config{Collections: []Collection{roads()}, ErrorRate: 1, ErrorStatus: http.StatusBadGateway})
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/collections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status %d", resp.StatusCode)
+	}
+
+	slow := NewServer(Config{Latency: time.Second})
+	defer slow.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", slow.URL+"/collections", nil)
+	start := time.Now()
+	if _, err := http.DefaultClient.Do(req.WithContext(ctx)); err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected timeout, got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestLoadCollection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wfstest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lakes.geojson")
+	ioutil.WriteFile(path, []byte(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "id": 1, "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {}}]}`), 0644)
+	c, err := LoadCollection(path)
+	if err != nil || c.Name != "lakes" || len(c.Features) != 1 {
+		t.Errorf("loaded %+v %v", c, err)
+	}
+	if _, err := LoadCollection(filepath.Join(dir, "missing.geojson")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
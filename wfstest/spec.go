@@ -0,0 +1,80 @@
+package wfstest
+
+import (
+	"net/http"
+)
+
+const specType = "application/vnd.oai.openapi+json;version=3.0"
+
+// serveSpec serves the OpenAPI document of the path layout in use, with the
+// server the request was made to.
+func (s *service) serveSpec(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, specType, s.spec(baseURL(r)))
+}
+
+type object map[string]interface{}
+
+func (s *service) spec(server string) object {
+	collectionID := parameter("collectionId", "path", "identifier of a collection", object{"type": "string"})
+	collectionID["required"] = true
+	featureID := parameter("featureId", "path", "identifier of a feature", object{"type": "string"})
+	featureID["required"] = true
+	items := operation("getFeatures", "Fetch features of the collection", geoJSON,
+		collectionID,
+		parameter("limit", "query", "maximum number of features to return", object{"type": "integer", "minimum": 1}),
+		parameter("startIndex", "query", "index of the first feature to return", object{"type": "integer", "minimum": 0}),
+		parameter("bbox", "query", "only features that intersect the bounding box lx,ly,ux,uy", object{"type": "array", "items": object{"type": "number"}, "minItems": 4, "maxItems": 4}),
+		parameter("datetime", "query", "only features with a time within the instant or interval", object{"type": "string"}),
+	)
+	item := operation("getFeature", "Fetch a single feature", geoJSON, collectionID, featureID)
+	collections := operation("describeCollections", "Describe the feature collections", "application/json")
+	var paths object
+	if s.cfg.OldStyle {
+		paths = object{
+			"/":                           object{"get": collections},
+			"/{collectionId}":             object{"get": items},
+			"/{collectionId}/{featureId}": object{"get": item},
+		}
+	} else {
+		paths = object{
+			"/":                                 object{"get": operation("getLandingPage", "Landing page", "application/json")},
+			"/conformance":                      object{"get": operation("getConformanceDeclaration", "Conformance classes implemented by the server", "application/json")},
+			"/collections":                      object{"get": collections},
+			"/collections/{collectionId}":       object{"get": operation("describeCollection", "Describe the feature collection", "application/json", collectionID)},
+			"/collections/{collectionId}/items": object{"get": items},
+			"/collections/{collectionId}/items/{featureId}": object{"get": item},
+		}
+	}
+	return object{
+		"openapi": "3.0.2",
+		"info": object{
+			"title":   "wfstest",
+			"version": "1.0",
+		},
+		"servers": []object{{"url": server}},
+		"paths":   paths,
+	}
+}
+
+func parameter(name, in, description string, schema object) object {
+	return object{"name": name, "in": in, "description": description, "schema": schema}
+}
+
+const geoJSON = "application/geo+json"
+
+// operation returns a GET operation whose successful responses are of
+// mediaType and errors problem details.
+func operation(id, summary, mediaType string, params ...object) object {
+	op := object{
+		"operationId": id,
+		"summary":     summary,
+		"responses": object{
+			"200":     object{"description": "successful operation", "content": object{mediaType: object{}}},
+			"default": object{"description": "error", "content": object{"application/problem+json": object{}}},
+		},
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	return op
+}